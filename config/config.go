@@ -5,8 +5,12 @@
 package config
 
 import (
+	"regexp"
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 // Specification is the configuration specification for the extension. Configuration values can be applied
@@ -20,12 +24,48 @@ type Specification struct {
 	EnableLocationSelection bool   `json:"enableLocationSelection" split_words:"true" required:"false"`
 	CloudApiToken           string `json:"cloudApiToken" split_words:"true" required:"false"`
 	CloudApiBaseUrl         string `json:"CloudApiBaseUrl" split_words:"true" required:"false" default:"https://api.k6.io"`
+	// CloudStackId is the id of the Grafana Cloud stack the cloud api token belongs to.
+	CloudStackId string `json:"cloudStackId" split_words:"true" required:"false"`
+	// CloudCredentialsDir is a directory with a file per named cloud credential, e.g. a mounted secret.
+	CloudCredentialsDir string `json:"cloudCredentialsDir" split_words:"true" required:"false"`
+	// StopGracePeriod is how long k6 may take to exit after being interrupted, before it is killed.
+	StopGracePeriod time.Duration `json:"stopGracePeriod" split_words:"true" required:"false" default:"30s"`
+	// SecretEnvironmentKeyPattern matches the keys of environment variables whose values are masked in logs and messages.
+	SecretEnvironmentKeyPattern string `json:"secretEnvironmentKeyPattern" split_words:"true" required:"false" default:"(?i)(password|passwd|secret|token|api[-_]?key|credential|private[-_]?key)"`
+	// LocationRefreshInterval is how often the live attributes of the k6 location, like the running tests, are refreshed.
+	LocationRefreshInterval time.Duration `json:"locationRefreshInterval" split_words:"true" required:"false" default:"10s"`
+	// MaxConcurrentRuns limits the load tests run by the extension at the same time, 0 for no limit.
+	MaxConcurrentRuns int `json:"maxConcurrentRuns" split_words:"true" required:"false" default:"0"`
+	// MaxTotalVus limits the VUs of all load tests run by the extension at the same time, 0 for no limit.
+	MaxTotalVus int `json:"maxTotalVus" split_words:"true" required:"false" default:"0"`
+	// QueueTimeout is how long load tests wait to be started if a limit is reached, they are rejected immediately if 0.
+	QueueTimeout time.Duration `json:"queueTimeout" split_words:"true" required:"false" default:"0s"`
+	// RunCpuLimit limits the CPU cores of each k6 process, 0 for no limit.
+	RunCpuLimit float64 `json:"runCpuLimit" split_words:"true" required:"false" default:"0"`
+	// RunMemoryLimit limits the memory of each k6 process, e.g. 512Mi, 0 for no limit.
+	RunMemoryLimit ByteSize `json:"runMemoryLimit" split_words:"true" required:"false" default:"0"`
+	// K6EnvAllowList are the environment variables of the extension passed to k6, a trailing * matches any suffix.
+	K6EnvAllowList []string `json:"k6EnvAllowList" split_words:"true" required:"false" default:"PATH,HOME,USER,TMPDIR,TZ,LANG,LC_*,HTTP_PROXY,HTTPS_PROXY,NO_PROXY,http_proxy,https_proxy,no_proxy,SSL_CERT_FILE,SSL_CERT_DIR,K6_*"`
+	// Outputs are additional k6 outputs by name, as JSON object, e.g. {"prometheus": {"output": "experimental-prometheus-rw", "env": {"K6_PROMETHEUS_RW_SERVER_URL": "http://prometheus:9090/api/v1/write"}, "default": true}}.
+	Outputs Outputs `json:"outputs" split_words:"true" required:"false"`
 }
 
 var (
 	Config Specification
 )
 
+// ByteSize is an amount of bytes, configured as quantity like 512Mi or 1G.
+type ByteSize int64
+
+func (b *ByteSize) Decode(value string) error {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return err
+	}
+	*b = ByteSize(quantity.Value())
+	return nil
+}
+
 func ParseConfiguration() {
 	err := envconfig.Process("steadybit_extension", &Config)
 	if err != nil {
@@ -34,5 +74,16 @@ func ParseConfiguration() {
 }
 
 func ValidateConfiguration() {
-	// You may optionally validate the configuration here.
+	if _, err := regexp.Compile(Config.SecretEnvironmentKeyPattern); err != nil {
+		log.Fatal().Err(err).Msgf("Invalid secret environment key pattern.")
+	}
+	if Config.RunCpuLimit < 0 || Config.RunMemoryLimit < 0 {
+		log.Fatal().Msgf("The resource limits of k6 processes must not be negative.")
+	}
+	if Config.LocationRefreshInterval <= 0 {
+		log.Fatal().Msgf("The location refresh interval must be positive, but is %s.", Config.LocationRefreshInterval)
+	}
+	if _, err := CloudCredentials(); err != nil {
+		log.Fatal().Err(err).Msgf("Invalid cloud credentials.")
+	}
 }
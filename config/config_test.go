@@ -0,0 +1,63 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResourceLimits(t *testing.T) {
+	t.Setenv("STEADYBIT_EXTENSION_RUN_CPU_LIMIT", "1.5")
+	t.Setenv("STEADYBIT_EXTENSION_RUN_MEMORY_LIMIT", "512Mi")
+	t.Setenv("STEADYBIT_EXTENSION_K6_ENV_ALLOW_LIST", "PATH,K6_*")
+	t.Cleanup(func() { Config = Specification{} })
+
+	ParseConfiguration()
+
+	assert.Equal(t, 1.5, Config.RunCpuLimit)
+	assert.Equal(t, ByteSize(512*1024*1024), Config.RunMemoryLimit)
+	assert.Equal(t, []string{"PATH", "K6_*"}, Config.K6EnvAllowList)
+}
+
+func TestParseResourceLimitsDefaults(t *testing.T) {
+	t.Cleanup(func() { Config = Specification{} })
+
+	ParseConfiguration()
+
+	assert.Zero(t, Config.RunCpuLimit)
+	assert.Zero(t, Config.RunMemoryLimit)
+	assert.Contains(t, Config.K6EnvAllowList, "PATH")
+	assert.Contains(t, Config.K6EnvAllowList, "HTTPS_PROXY")
+	assert.NotContains(t, Config.K6EnvAllowList, "STEADYBIT_EXTENSION_*")
+}
+
+func TestDecodeByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "1G", want: 1000 * 1000 * 1000},
+		{value: "256Mi", want: 256 * 1024 * 1024},
+		{value: "1048576", want: 1024 * 1024},
+		{value: "lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var size ByteSize
+			err := size.Decode(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, size)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultCloudCredential is the name of the credential given by the cloud api token
+// and stack id configured directly.
+const DefaultCloudCredential = "default"
+
+// CloudCredential authenticates at the Grafana Cloud k6 API for a stack.
+type CloudCredential struct {
+	Token   string `json:"token"`
+	StackId string `json:"stackId"`
+}
+
+// CloudCredentials returns the configured cloud credentials by name. Besides the
+// default credential, every file in the credentials directory is a credential
+// named after the file. Its content is either the token or a JSON object with the
+// token and the stack id. The directory is read on every call, so that mounted
+// secrets can be rotated.
+func CloudCredentials() (map[string]CloudCredential, error) {
+	credentials := make(map[string]CloudCredential)
+	if Config.CloudApiToken != "" {
+		credentials[DefaultCloudCredential] = CloudCredential{Token: Config.CloudApiToken, StackId: Config.CloudStackId}
+	}
+	if Config.CloudCredentialsDir == "" {
+		return credentials, nil
+	}
+
+	entries, err := os.ReadDir(Config.CloudCredentialsDir)
+	if err != nil {
+		return credentials, fmt.Errorf("failed to read cloud credentials: %w", err)
+	}
+	for _, entry := range entries {
+		// mounted secrets contain hidden folders and symlinks to them besides the keys
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(Config.CloudCredentialsDir, entry.Name())
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		credential, err := readCloudCredential(path)
+		if err != nil {
+			return credentials, fmt.Errorf("failed to read cloud credential %s: %w", entry.Name(), err)
+		}
+		credentials[entry.Name()] = credential
+	}
+	return credentials, nil
+}
+
+func readCloudCredential(path string) (CloudCredential, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return CloudCredential{}, err
+	}
+	trimmed := strings.TrimSpace(string(content))
+	credential := CloudCredential{Token: trimmed}
+	if strings.HasPrefix(trimmed, "{") {
+		credential = CloudCredential{}
+		if err := json.Unmarshal([]byte(trimmed), &credential); err != nil {
+			return CloudCredential{}, err
+		}
+	}
+	if credential.Token == "" {
+		return CloudCredential{}, fmt.Errorf("the token is empty")
+	}
+	return credential, nil
+}
+
+// CloudCredentialNames returns the sorted names of the configured cloud credentials.
+func CloudCredentialNames() []string {
+	credentials, _ := CloudCredentials()
+	names := make([]string, 0, len(credentials))
+	for name := range credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultCloudCredentialName returns the credential used if none is selected, the
+// default credential if configured or else the first one.
+func DefaultCloudCredentialName() string {
+	names := CloudCredentialNames()
+	for _, name := range names {
+		if name == DefaultCloudCredential {
+			return name
+		}
+	}
+	if len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+func GetCloudCredential(name string) (CloudCredential, error) {
+	credentials, err := CloudCredentials()
+	if credential, ok := credentials[name]; ok {
+		return credential, nil
+	}
+	if err != nil {
+		return CloudCredential{}, err
+	}
+	return CloudCredential{}, fmt.Errorf("unknown cloud credential '%s'", name)
+}
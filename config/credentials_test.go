@@ -0,0 +1,62 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudCredentials(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team-a"), []byte("token-a\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team-b"), []byte(`{"token": "token-b", "stackId": "42"}`), 0600))
+	// mounted secrets link their keys to a hidden folder
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "..data", "team-a"), []byte("token-a"), 0600))
+	Config = Specification{CloudApiToken: "token", CloudStackId: "1", CloudCredentialsDir: dir}
+	t.Cleanup(func() { Config = Specification{} })
+
+	credentials, err := CloudCredentials()
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]CloudCredential{
+		"default": {Token: "token", StackId: "1"},
+		"team-a":  {Token: "token-a"},
+		"team-b":  {Token: "token-b", StackId: "42"},
+	}, credentials)
+	assert.Equal(t, []string{"default", "team-a", "team-b"}, CloudCredentialNames())
+	assert.Equal(t, "default", DefaultCloudCredentialName())
+}
+
+func TestCloudCredentialsWithoutDefault(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team-b"), []byte("token-b"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team-a"), []byte("token-a"), 0600))
+	Config = Specification{CloudCredentialsDir: dir}
+	t.Cleanup(func() { Config = Specification{} })
+
+	assert.Equal(t, "team-a", DefaultCloudCredentialName())
+	credential, err := GetCloudCredential("team-b")
+	require.NoError(t, err)
+	assert.Equal(t, "token-b", credential.Token)
+	_, err = GetCloudCredential("default")
+	assert.EqualError(t, err, "unknown cloud credential 'default'")
+}
+
+func TestCloudCredentialsRejectsEmptyToken(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team-a"), []byte(`{"stackId": "42"}`), 0600))
+	Config = Specification{CloudCredentialsDir: dir}
+	t.Cleanup(func() { Config = Specification{} })
+
+	_, err := CloudCredentials()
+
+	assert.EqualError(t, err, "failed to read cloud credential team-a: the token is empty")
+}
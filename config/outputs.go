@@ -0,0 +1,66 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Output is an additional k6 output load tests can stream their results to, e.g.
+// Prometheus remote write, InfluxDB, OpenTelemetry, StatsD or CSV.
+type Output struct {
+	// Output is passed to k6 as --out, e.g. experimental-prometheus-rw or csv=/tmp/results.csv.
+	Output string `json:"output"`
+	// Env configures the output, e.g. K6_PROMETHEUS_RW_SERVER_URL.
+	Env map[string]string `json:"env"`
+	// Default outputs are used if the load test doesn't select any.
+	Default bool `json:"default"`
+}
+
+// Outputs are the additional k6 outputs by name, configured as JSON object.
+type Outputs map[string]Output
+
+func (o *Outputs) Decode(value string) error {
+	outputs := make(map[string]Output)
+	if strings.TrimSpace(value) != "" {
+		if err := json.Unmarshal([]byte(value), &outputs); err != nil {
+			return err
+		}
+	}
+	for name, output := range outputs {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("the name of an output must not be empty")
+		}
+		if strings.TrimSpace(output.Output) == "" {
+			return fmt.Errorf("the output %s must define the k6 output to use", name)
+		}
+	}
+	*o = outputs
+	return nil
+}
+
+// Names returns the sorted names of the outputs.
+func (o Outputs) Names() []string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Defaults returns the sorted names of the outputs used by default.
+func (o Outputs) Defaults() []string {
+	names := make([]string, 0)
+	for _, name := range o.Names() {
+		if o[name].Default {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputs(t *testing.T) {
+	t.Setenv("STEADYBIT_EXTENSION_OUTPUTS", `{
+		"prometheus": {"output": "experimental-prometheus-rw", "env": {"K6_PROMETHEUS_RW_SERVER_URL": "http://prometheus:9090/api/v1/write"}, "default": true},
+		"csv": {"output": "csv=/tmp/results.csv"}
+	}`)
+	t.Cleanup(func() { Config = Specification{} })
+
+	ParseConfiguration()
+
+	assert.Equal(t, Outputs{
+		"prometheus": {Output: "experimental-prometheus-rw", Env: map[string]string{"K6_PROMETHEUS_RW_SERVER_URL": "http://prometheus:9090/api/v1/write"}, Default: true},
+		"csv":        {Output: "csv=/tmp/results.csv"},
+	}, Config.Outputs)
+	assert.Equal(t, []string{"csv", "prometheus"}, Config.Outputs.Names())
+	assert.Equal(t, []string{"prometheus"}, Config.Outputs.Defaults())
+}
+
+func TestDecodeOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "empty", value: ""},
+		{name: "valid", value: `{"statsd": {"output": "statsd"}}`},
+		{name: "invalid json", value: `{"statsd": `, wantErr: "unexpected end of JSON input"},
+		{name: "missing output", value: `{"statsd": {"env": {"K6_STATSD_ADDR": "localhost:8125"}}}`, wantErr: "the output statsd must define the k6 output to use"},
+		{name: "missing name", value: `{"": {"output": "statsd"}}`, wantErr: "the name of an output must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outputs Outputs
+			err := outputs.Decode(tt.value)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.NotNil(t, outputs)
+			}
+		})
+	}
+}
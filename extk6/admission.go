@@ -0,0 +1,222 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// abandonedQueueGracePeriod is how long queued runs are kept beyond the queue
+// timeout, before they are considered abandoned by the agent.
+const abandonedQueueGracePeriod = time.Minute
+
+// inspectTimeout is how long k6 may take to compute the execution requirements of a script.
+const inspectTimeout = 30 * time.Second
+
+// k6InspectCommand prints the options and the execution requirements of the script appended.
+var k6InspectCommand = []string{"k6", "inspect", "--execution-requirements"}
+
+// admission limits the load tests run by this extension instance at the same
+// time, so that they don't exhaust the container's resources.
+type admission struct {
+	mutex sync.Mutex
+	// running holds the VUs of the admitted runs by execution id.
+	running map[string]int
+	// queue holds the runs waiting for admission in order.
+	queue []queuedRun
+}
+
+type queuedRun struct {
+	id    string
+	since time.Time
+}
+
+var admissions = &admission{running: make(map[string]int)}
+
+// admit admits the run if the limits allow it and no run has been queued before
+// it. Otherwise, it is queued if enqueue is set, and the reason is returned.
+func (a *admission) admit(id string, vus int, now time.Time, enqueue bool) (bool, string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if _, ok := a.running[id]; ok {
+		return true, ""
+	}
+
+	a.queue = slices.DeleteFunc(a.queue, func(run queuedRun) bool {
+		return now.Sub(run.since) > config.Config.QueueTimeout+abandonedQueueGracePeriod
+	})
+	position := slices.IndexFunc(a.queue, func(run queuedRun) bool { return run.id == id })
+	reason := a.exceeded(vus)
+	if reason == "" && (position == 0 || (position == -1 && len(a.queue) == 0)) {
+		if position == 0 {
+			a.queue = a.queue[1:]
+		}
+		a.running[id] = vus
+		return true, ""
+	}
+	if reason == "" {
+		ahead := position
+		if position == -1 {
+			ahead = len(a.queue)
+		}
+		reason = fmt.Sprintf("%d load tests are waiting before it", ahead)
+	}
+	if position == -1 && enqueue {
+		a.queue = append(a.queue, queuedRun{id: id, since: now})
+	}
+	return false, reason
+}
+
+// exceeded returns which limit the run would exceed, if any.
+func (a *admission) exceeded(vus int) string {
+	if limit := config.Config.MaxConcurrentRuns; limit > 0 && len(a.running) >= limit {
+		return fmt.Sprintf("%d of at most %d load tests are running", len(a.running), limit)
+	}
+	if limit := config.Config.MaxTotalVus; limit > 0 {
+		total := 0
+		for _, running := range a.running {
+			total += running
+		}
+		if total+vus > limit {
+			return fmt.Sprintf("%d of at most %d VUs are in use", total, limit)
+		}
+	}
+	return ""
+}
+
+// release frees the limits taken by the run.
+func (a *admission) release(id string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.running, id)
+}
+
+// dequeue removes the run from the queue.
+func (a *admission) dequeue(id string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.queue = slices.DeleteFunc(a.queue, func(run queuedRun) bool { return run.id == id })
+}
+
+// declaredVus returns the VUs the run is configured with, the maximum of the
+// stages' targets, or the single VU k6 runs by default.
+func declaredVus(runConfig K6LoadTestRunConfig) int {
+	vus := runConfig.Vus
+	for _, stage := range runConfig.Stages {
+		if target, err := strconv.Atoi(stage["value"]); err == nil && target > vus {
+			vus = target
+		}
+	}
+	return max(vus, 1)
+}
+
+// requiredVus returns the VUs the run needs. The VUs configured by the action
+// override the script's options, otherwise the maximum VUs of the script's
+// scenarios are computed by k6 inspect, falling back to declaredVus.
+func requiredVus(runConfig K6LoadTestRunConfig, workDir string, script string) int {
+	if runConfig.Vus > 0 || len(runConfig.Stages) > 0 {
+		return declaredVus(runConfig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, k6InspectCommand[0], append(k6InspectCommand[1:], script)...)
+	cmd.Dir = workDir
+	cmd.Env = scrubbedEnv(os.Environ(), config.Config.K6EnvAllowList)
+	output, err := cmd.Output()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to inspect the VUs of the k6 script, counting it as a single VU.")
+		return declaredVus(runConfig)
+	}
+	var requirements struct {
+		MaxVUs int `json:"maxVUs"`
+	}
+	if err := json.Unmarshal(output, &requirements); err != nil {
+		log.Warn().Err(err).Msg("Failed to parse the execution requirements of the k6 script, counting it as a single VU.")
+		return declaredVus(runConfig)
+	}
+	return max(requirements.MaxVUs, 1)
+}
+
+// startAdmitted starts k6 if the run is admitted. Otherwise, it is queued if a
+// queue timeout is configured, to be started by statusQueued, or rejected.
+func startAdmitted(state *K6LoadTestRunState, env []string) (*action_kit_api.StartResult, error) {
+	now := time.Now()
+	admitted, reason := admissions.admit(state.ExecutionId.String(), state.Vus, now, config.Config.QueueTimeout > 0)
+	if admitted {
+		return startServingApi(state, env)
+	}
+	if config.Config.QueueTimeout <= 0 {
+		log.Info().Msgf("Rejecting load test, %s.", reason)
+		return &action_kit_api.StartResult{
+			Error: &action_kit_api.ActionKitError{
+				Status: extutil.Ptr(action_kit_api.Errored),
+				Title:  fmt.Sprintf("The load test cannot be started on this location, as %s.", reason),
+			},
+		}, nil
+	}
+
+	log.Info().Msgf("Queueing load test, %s.", reason)
+	state.QueuedAt = &now
+	return &action_kit_api.StartResult{
+		Messages: &[]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("The load test is queued, as %s. It waits up to %s to be started.", reason, config.Config.QueueTimeout),
+			},
+		},
+	}, nil
+}
+
+// statusQueued starts the queued run once it is admitted, or fails it once the
+// queue timeout has passed.
+func statusQueued(state *K6LoadTestRunState, env []string) (*action_kit_api.StatusResult, error) {
+	now := time.Now()
+	id := state.ExecutionId.String()
+	waited := now.Sub(*state.QueuedAt).Round(time.Second)
+	if admitted, _ := admissions.admit(id, state.Vus, now, true); admitted {
+		state.QueuedAt = nil
+		started, err := startServingApi(state, env)
+		if err != nil {
+			return nil, err
+		}
+		messages := []action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("The load test was started after waiting %s in the queue.", waited),
+			},
+		}
+		if started != nil && started.Messages != nil {
+			messages = append(messages, *started.Messages...)
+		}
+		return &action_kit_api.StatusResult{Messages: &messages}, nil
+	}
+
+	if now.Sub(*state.QueuedAt) > config.Config.QueueTimeout {
+		admissions.dequeue(id)
+		state.QueuedAt = nil
+		return &action_kit_api.StatusResult{
+			Completed: true,
+			Error: &action_kit_api.ActionKitError{
+				Status: extutil.Ptr(action_kit_api.Errored),
+				Title:  fmt.Sprintf("The load test was not started within the queue timeout of %s.", config.Config.QueueTimeout),
+			},
+		}, nil
+	}
+	return &action_kit_api.StatusResult{Messages: &[]action_kit_api.Message{}}, nil
+}
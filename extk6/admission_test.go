@@ -0,0 +1,197 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withLimits configures the admission limits and resets the admitted runs for the test.
+func withLimits(t *testing.T, maxRuns int, maxVus int, queueTimeout time.Duration) {
+	previous, previousAdmissions := config.Config, admissions
+	config.Config.MaxConcurrentRuns = maxRuns
+	config.Config.MaxTotalVus = maxVus
+	config.Config.QueueTimeout = queueTimeout
+	admissions = &admission{running: make(map[string]int)}
+	t.Cleanup(func() { config.Config, admissions = previous, previousAdmissions })
+}
+
+func Test_admission_limits_concurrent_runs_in_order(t *testing.T) {
+	withLimits(t, 1, 0, time.Minute)
+	now := time.Now()
+
+	admitted, _ := admissions.admit("a", 1, now, true)
+	assert.True(t, admitted)
+	admitted, reason := admissions.admit("b", 1, now, true)
+	assert.False(t, admitted)
+	assert.Equal(t, "1 of at most 1 load tests are running", reason)
+	admitted, _ = admissions.admit("c", 1, now, true)
+	assert.False(t, admitted)
+
+	admissions.release("a")
+	admitted, reason = admissions.admit("c", 1, now, true)
+	assert.False(t, admitted, "b was queued before c")
+	assert.Equal(t, "1 load tests are waiting before it", reason)
+	admitted, _ = admissions.admit("b", 1, now, true)
+	assert.True(t, admitted)
+}
+
+func Test_admission_limits_total_vus(t *testing.T) {
+	withLimits(t, 0, 100, 0)
+	now := time.Now()
+
+	admitted, _ := admissions.admit("a", 60, now, false)
+	assert.True(t, admitted)
+	admitted, reason := admissions.admit("b", 50, now, false)
+	assert.False(t, admitted)
+	assert.Equal(t, "60 of at most 100 VUs are in use", reason)
+	admitted, _ = admissions.admit("c", 40, now, false)
+	assert.True(t, admitted, "rejected runs are not queued")
+}
+
+func Test_admission_drops_abandoned_runs(t *testing.T) {
+	withLimits(t, 1, 0, time.Minute)
+	now := time.Now()
+	admissions.admit("a", 1, now, true)
+	admissions.admit("abandoned", 1, now, true)
+	admissions.release("a")
+
+	admitted, _ := admissions.admit("b", 1, now.Add(time.Minute+abandonedQueueGracePeriod+time.Second), true)
+
+	assert.True(t, admitted)
+}
+
+func Test_declaredVus(t *testing.T) {
+	assert.Equal(t, 1, declaredVus(K6LoadTestRunConfig{}))
+	assert.Equal(t, 10, declaredVus(K6LoadTestRunConfig{Vus: 10}))
+	assert.Equal(t, 50, declaredVus(K6LoadTestRunConfig{Vus: 5, Stages: []map[string]string{{"key": "1m", "value": "50"}, {"key": "1m", "value": "0"}}}))
+}
+
+func queuedState(t *testing.T, script string) *K6LoadTestRunState {
+	state := &K6LoadTestRunState{
+		Command:     []string{"sh", "-c", script},
+		ExecutionId: uuid.New(),
+		Vus:         1,
+	}
+	folder := fmt.Sprintf("/tmp/steadybit/%v", state.ExecutionId)
+	require.NoError(t, os.MkdirAll(folder, 0755))
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+	return state
+}
+
+func TestStartRejectsRunsExceedingLimits(t *testing.T) {
+	withLimits(t, 1, 0, 0)
+	admissions.admit("running", 1, time.Now(), false)
+	state := queuedState(t, "exit 0")
+
+	result, err := startAdmitted(state, nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "The load test cannot be started on this location, as 1 of at most 1 load tests are running.", result.Error.Title)
+	assert.Empty(t, state.CmdStateID)
+}
+
+func TestStartQueuesRunsUntilAdmitted(t *testing.T) {
+	withLimits(t, 1, 0, time.Minute)
+	admissions.admit("running", 1, time.Now(), false)
+	state := queuedState(t, "echo started")
+
+	result, err := startAdmitted(state, nil)
+	require.NoError(t, err)
+	require.NotNil(t, result.Messages)
+	assert.Equal(t, "The load test is queued, as 1 of at most 1 load tests are running. It waits up to 1m0s to be started.", (*result.Messages)[0].Message)
+	require.NotNil(t, state.QueuedAt)
+
+	status, err := statusQueued(state, nil)
+	require.NoError(t, err)
+	assert.False(t, status.Completed)
+	assert.Empty(t, state.CmdStateID)
+
+	admissions.release("running")
+	status, err = statusQueued(state, nil)
+	require.NoError(t, err)
+	assert.Contains(t, (*status.Messages)[0].Message, "The load test was started after waiting")
+	assert.Nil(t, state.QueuedAt)
+	assert.NotEmpty(t, state.CmdStateID)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	_, err = stop(state)
+	require.NoError(t, err)
+	admitted, _ := admissions.admit("next", 1, time.Now(), false)
+	assert.True(t, admitted, "the run must be released when it has exited")
+}
+
+func TestQueuedRunTimesOut(t *testing.T) {
+	withLimits(t, 1, 0, time.Minute)
+	admissions.admit("running", 1, time.Now(), false)
+	state := queuedState(t, "exit 0")
+	_, err := startAdmitted(state, nil)
+	require.NoError(t, err)
+	*state.QueuedAt = state.QueuedAt.Add(-2 * time.Minute)
+
+	status, err := statusQueued(state, nil)
+
+	require.NoError(t, err)
+	assert.True(t, status.Completed)
+	require.NotNil(t, status.Error)
+	assert.Equal(t, "The load test was not started within the queue timeout of 1m0s.", status.Error.Title)
+	assert.Empty(t, admissions.queue)
+}
+
+func TestPrepareRejectsVusExceedingLimit(t *testing.T) {
+	withLimits(t, 0, 100, 0)
+	action := NewK6LoadTestRunAction()
+	state := action.NewEmptyState()
+
+	result, err := action.Prepare(context.TODO(), &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": "test.js", "vus": 200},
+		ExecutionId: uuid.New(),
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "The load test with 200 VUs exceeds the limit of 100 VUs of this location.", result.Error.Title)
+}
+
+func TestPrepareRejectsScriptVusExceedingLimit(t *testing.T) {
+	withLimits(t, 0, 100, 0)
+	previous := k6InspectCommand
+	k6InspectCommand = []string{"sh", "-c", `printf '{"vus": 1, "maxVUs": 250, "totalDuration": "1m"}'`}
+	t.Cleanup(func() { k6InspectCommand = previous })
+	action := NewK6LoadTestRunAction()
+	state := action.NewEmptyState()
+
+	result, err := action.Prepare(context.TODO(), &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": "test.js"},
+		ExecutionId: uuid.New(),
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "The load test with 250 VUs exceeds the limit of 100 VUs of this location.", result.Error.Title)
+}
+
+func Test_requiredVus(t *testing.T) {
+	previous := k6InspectCommand
+	t.Cleanup(func() { k6InspectCommand = previous })
+
+	k6InspectCommand = []string{"sh", "-c", `printf '{"maxVUs": 30}'`}
+	assert.Equal(t, 30, requiredVus(K6LoadTestRunConfig{}, "", "test.js"))
+	assert.Equal(t, 5, requiredVus(K6LoadTestRunConfig{Vus: 5}, "", "test.js"), "the action's VUs override the script's")
+
+	k6InspectCommand = []string{"sh", "-c", "exit 1"}
+	assert.Equal(t, 1, requiredVus(K6LoadTestRunConfig{}, "", "test.js"))
+}
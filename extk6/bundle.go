@@ -0,0 +1,223 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// defaultEntrypoints are tried in order if no entrypoint is configured for a bundle.
+var defaultEntrypoints = []string{"script.js", "main.js"}
+
+// maxBundleSize limits the total size of the files extracted from a bundle.
+const maxBundleSize = 512 << 20
+
+// resolveScript returns the script k6 is started with and the directory it has to
+// be started in. Plain scripts and k6 archives are run as they are, zip and tar
+// bundles are extracted into the execution's folder first.
+func resolveScript(executionId uuid.UUID, file string, entrypoint string) (string, string, error) {
+	lower := strings.ToLower(file)
+	workDir := fmt.Sprintf("/tmp/steadybit/%v/bundle", executionId) //Folder is managed by action_kit_sdk's file download handling
+
+	var err error
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		err = extractZip(file, workDir)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = extractTarGz(file, workDir)
+	case strings.HasSuffix(lower, ".tar"):
+		var isArchive bool
+		if isArchive, err = isK6Archive(file); err == nil && isArchive {
+			return file, "", nil
+		} else if err == nil {
+			err = extractTar(file, workDir)
+		}
+	default:
+		return file, "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to extract %s: %w", filepath.Base(file), err)
+	}
+
+	baseDir, err := bundleBaseDir(workDir)
+	if err != nil {
+		return "", "", err
+	}
+	script, err := findEntrypoint(baseDir, entrypoint)
+	if err != nil {
+		return "", "", err
+	}
+	return script, baseDir, nil
+}
+
+// bundleBaseDir descends into the single top-level directory of a bundle, which is
+// what archiving a whole folder results in.
+func bundleBaseDir(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+func findEntrypoint(dir string, entrypoint string) (string, error) {
+	if entrypoint != "" {
+		path, err := safeJoin(dir, entrypoint)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("entrypoint %s not found in bundle", entrypoint)
+		}
+		return filepath.Rel(dir, path)
+	}
+
+	for _, candidate := range defaultEntrypoints {
+		if _, err := os.Stat(filepath.Join(dir, candidate)); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("bundle contains none of %s, please configure the entrypoint", strings.Join(defaultEntrypoints, ", "))
+}
+
+// isK6Archive reports whether the tar file was created by `k6 archive`, which k6 can
+// run without extracting it.
+func isK6Archive(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	r := tar.NewReader(f)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if filepath.Clean(header.Name) == "metadata.json" {
+			return true, nil
+		}
+	}
+}
+
+func extractZip(file string, dst string) error {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	budget := int64(maxBundleSize)
+	for _, entry := range r.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		in, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		budget, err = extractFile(in, dst, entry.Name, budget)
+		_ = in.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTarGz(file string, dst string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = gz.Close() }()
+	return extractTarStream(gz, dst)
+}
+
+func extractTar(file string, dst string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return extractTarStream(f, dst)
+}
+
+func extractTarStream(in io.Reader, dst string) error {
+	r := tar.NewReader(in)
+	budget := int64(maxBundleSize)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// links and devices are skipped, they are of no use for k6 scripts
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if budget, err = extractFile(r, dst, header.Name, budget); err != nil {
+			return err
+		}
+	}
+}
+
+// extractFile writes in to name below dst and returns the remaining size budget.
+func extractFile(in io.Reader, dst string, name string, budget int64) (int64, error) {
+	path, err := safeJoin(dst, name)
+	if err != nil {
+		return budget, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return budget, err
+	}
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return budget, err
+	}
+	defer func() { _ = out.Close() }()
+
+	written, err := io.CopyN(out, in, budget+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return budget, err
+	}
+	if written > budget {
+		return budget, fmt.Errorf("bundle exceeds the maximum size of %d MiB", maxBundleSize>>20)
+	}
+	return budget - written, nil
+}
+
+// safeJoin joins name to dir and rejects names escaping dir.
+func safeJoin(dir string, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal path %s in bundle", name)
+	}
+	return path, nil
+}
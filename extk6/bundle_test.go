@@ -0,0 +1,138 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zipBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		entry, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = entry.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func tarBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for name, content := range files {
+		require.NoError(t, w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func newExecution(t *testing.T) uuid.UUID {
+	t.Helper()
+	executionId := uuid.New()
+	t.Cleanup(func() { _ = os.RemoveAll(fmt.Sprintf("/tmp/steadybit/%v", executionId)) })
+	return executionId
+}
+
+func Test_resolveScript_plain_script(t *testing.T) {
+	script, workDir, err := resolveScript(newExecution(t), "/tmp/steadybit/x/test.js", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/steadybit/x/test.js", script)
+	assert.Empty(t, workDir)
+}
+
+func Test_resolveScript_zip_with_default_entrypoint(t *testing.T) {
+	file := writeFile(t, "bundle.zip", zipBundle(t, map[string]string{
+		"main.js":     "import { login } from './lib/auth.js';",
+		"lib/auth.js": "export function login() {}",
+	}))
+
+	script, workDir, err := resolveScript(newExecution(t), file, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "main.js", script)
+	assert.FileExists(t, filepath.Join(workDir, "lib", "auth.js"))
+}
+
+func Test_resolveScript_tar_gz_with_top_level_folder_and_entrypoint(t *testing.T) {
+	file := writeFile(t, "bundle.tar.gz", gzipped(t, tarBundle(t, map[string]string{
+		"loadtest/tests/checkout.js": "export default function() {}",
+		"loadtest/data/users.csv":    "user1",
+	})))
+
+	script, workDir, err := resolveScript(newExecution(t), file, "tests/checkout.js")
+
+	require.NoError(t, err)
+	assert.Equal(t, "tests/checkout.js", script)
+	assert.Equal(t, "loadtest", filepath.Base(workDir))
+	assert.FileExists(t, filepath.Join(workDir, "data", "users.csv"))
+}
+
+func Test_resolveScript_k6_archive_is_run_as_is(t *testing.T) {
+	file := writeFile(t, "archive.tar", tarBundle(t, map[string]string{
+		"metadata.json":          "{}",
+		"data":                   "",
+		"file/home/me/script.js": "export default function() {}",
+	}))
+
+	script, workDir, err := resolveScript(newExecution(t), file, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, file, script)
+	assert.Empty(t, workDir)
+}
+
+func Test_resolveScript_rejects_missing_entrypoint(t *testing.T) {
+	file := writeFile(t, "bundle.zip", zipBundle(t, map[string]string{"other.js": ""}))
+
+	_, _, err := resolveScript(newExecution(t), file, "")
+	assert.ErrorContains(t, err, "please configure the entrypoint")
+
+	_, _, err = resolveScript(newExecution(t), file, "absent.js")
+	assert.ErrorContains(t, err, "entrypoint absent.js not found")
+
+	_, _, err = resolveScript(newExecution(t), file, "../../../etc/passwd")
+	assert.ErrorContains(t, err, "illegal path")
+}
+
+func Test_resolveScript_rejects_path_traversal(t *testing.T) {
+	file := writeFile(t, "bundle.tar", tarBundle(t, map[string]string{
+		"script.js":         "",
+		"../../evil.sh":     "",
+		"lib/../../evil.sh": "",
+	}))
+
+	_, _, err := resolveScript(newExecution(t), file, "")
+
+	assert.ErrorContains(t, err, "illegal path")
+}
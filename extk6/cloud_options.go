@@ -0,0 +1,167 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/k6cloud"
+)
+
+// cloudWrapperPrefix is prepended to the script's file name for the generated
+// script which overrides the script's cloud options.
+const cloudWrapperPrefix = ".steadybit-cloud-"
+
+// cloudWrapperTemplate re-exports everything of the script, with the cloud options
+// merged into the script's options.
+const cloudWrapperTemplate = `import * as script from %[1]s;
+export * from %[1]s;
+export default script.default;
+export const options = Object.assign({}, script.options, {
+  cloud: Object.assign({}, (script.options || {}).cloud, %[2]s),
+});
+`
+
+type loadZoneShare struct {
+	LoadZone string `json:"loadZone"`
+	Percent  int    `json:"percent"`
+}
+
+func credentialParameter(names []string, defaultName string) action_kit_api.ActionParameter {
+	options := make([]action_kit_api.ParameterOption, 0, len(names))
+	for _, name := range names {
+		options = append(options, action_kit_api.ExplicitParameterOption{Label: name, Value: name})
+	}
+	return action_kit_api.ActionParameter{
+		Name:         "credential",
+		Label:        "Cloud credential",
+		Description:  new("Credential to run the test with, which determines the Grafana Cloud stack it is run and billed in."),
+		Type:         action_kit_api.ActionParameterTypeString,
+		Required:     new(true),
+		DefaultValue: new(defaultName),
+		Options:      &options,
+		Order:        new(10),
+	}
+}
+
+func cloudParameters(loadZones []k6cloud.LoadZone) []action_kit_api.ActionParameter {
+	var loadZoneOptions *[]action_kit_api.ParameterOption
+	if len(loadZones) > 0 {
+		options := make([]action_kit_api.ParameterOption, 0, len(loadZones))
+		for _, zone := range loadZones {
+			label := zone.K6LoadZoneId
+			if zone.Name != "" {
+				label = fmt.Sprintf("%s (%s)", zone.Name, zone.K6LoadZoneId)
+			}
+			options = append(options, action_kit_api.ExplicitParameterOption{Label: label, Value: zone.K6LoadZoneId})
+		}
+		loadZoneOptions = &options
+	}
+	return []action_kit_api.ActionParameter{
+		{
+			Name:        "projectId",
+			Label:       "Project ID",
+			Description: new("Grafana Cloud k6 project to run the test in, overrides the script's cloud options."),
+			Type:        action_kit_api.ActionParameterTypeInteger,
+			Required:    new(false),
+			MinValue:    new(1),
+			Order:       new(12),
+		},
+		{
+			Name:        "testName",
+			Label:       "Test name",
+			Description: new("Name of the test in Grafana Cloud k6, overrides the script's cloud options."),
+			Type:        action_kit_api.ActionParameterTypeString,
+			Required:    new(false),
+			Order:       new(13),
+		},
+		{
+			Name:        "loadZones",
+			Label:       "Load zones",
+			Description: new("Distribute the load across load zones, overriding the script's cloud options. Use the load zone (e.g. amazon:us:ashburn) as key and its share in percent as value, the shares must add up to 100."),
+			Type:        action_kit_api.ActionParameterTypeKeyValue,
+			Required:    new(false),
+			Options:     loadZoneOptions,
+			Order:       new(14),
+		},
+	}
+}
+
+// cloudOptions returns the script's cloud options to override. Load zones are
+// validated against the available ones, if they are known.
+func cloudOptions(runConfig K6LoadTestRunConfig, available []k6cloud.LoadZone) (map[string]any, error) {
+	options := make(map[string]any)
+	if runConfig.ProjectId < 0 {
+		return nil, errors.New("the project id must be positive")
+	} else if runConfig.ProjectId > 0 {
+		options["projectID"] = runConfig.ProjectId
+	}
+	if name := strings.TrimSpace(runConfig.TestName); name != "" {
+		options["name"] = name
+	}
+
+	if len(runConfig.LoadZones) == 0 {
+		return options, nil
+	}
+	availableIds := make([]string, 0, len(available))
+	for _, zone := range available {
+		availableIds = append(availableIds, zone.K6LoadZoneId)
+	}
+	distribution := make(map[string]loadZoneShare)
+	total := 0
+	for i, entry := range runConfig.LoadZones {
+		zone := strings.TrimSpace(entry["key"])
+		if zone == "" {
+			return nil, errors.New("the load zone must not be empty")
+		}
+		if len(availableIds) > 0 && !slices.Contains(availableIds, zone) {
+			return nil, fmt.Errorf("unknown load zone '%s'", zone)
+		}
+		percent, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(entry["value"]), "%"))
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid share '%s' of load zone '%s', expected a percentage between 1 and 100", entry["value"], zone)
+		}
+		distribution[fmt.Sprintf("zone%d", i+1)] = loadZoneShare{LoadZone: zone, Percent: percent}
+		total += percent
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("the shares of the load zones add up to %d%% instead of 100%%", total)
+	}
+	options["distribution"] = distribution
+	return options, nil
+}
+
+// writeCloudWrapper writes a script next to the given one which runs it with the
+// cloud options overridden, and returns its path. Relative scripts, e.g. the
+// entrypoint of a bundle, are resolved against workDir, which k6 runs in.
+func writeCloudWrapper(workDir string, script string, options map[string]any) (string, error) {
+	overrides, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Base(script)
+	imported, err := json.Marshal("./" + base)
+	if err != nil {
+		return "", err
+	}
+	wrapper := filepath.Join(filepath.Dir(script), cloudWrapperPrefix+base)
+	path := wrapper
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	content := fmt.Sprintf(cloudWrapperTemplate, imported, overrides)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return wrapper, nil
+}
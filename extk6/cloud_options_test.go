@@ -0,0 +1,262 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-k6/k6cloud"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cloudOptions(t *testing.T) {
+	available := []k6cloud.LoadZone{{K6LoadZoneId: "amazon:us:ashburn"}, {K6LoadZoneId: "amazon:de:frankfurt"}}
+	tests := []struct {
+		name      string
+		runConfig K6LoadTestRunConfig
+		available []k6cloud.LoadZone
+		want      map[string]any
+		wantErr   string
+	}{
+		{
+			name:      "nothing to override",
+			runConfig: K6LoadTestRunConfig{},
+			want:      map[string]any{},
+		},
+		{
+			name:      "project and name",
+			runConfig: K6LoadTestRunConfig{ProjectId: 42, TestName: " checkout "},
+			want:      map[string]any{"projectID": 42, "name": "checkout"},
+		},
+		{
+			name:      "distribution",
+			runConfig: K6LoadTestRunConfig{LoadZones: []map[string]string{{"key": "amazon:us:ashburn", "value": "60"}, {"key": "amazon:de:frankfurt", "value": "40%"}}},
+			available: available,
+			want: map[string]any{"distribution": map[string]loadZoneShare{
+				"zone1": {LoadZone: "amazon:us:ashburn", Percent: 60},
+				"zone2": {LoadZone: "amazon:de:frankfurt", Percent: 40},
+			}},
+		},
+		{
+			name:      "zones are not validated if unknown",
+			runConfig: K6LoadTestRunConfig{LoadZones: []map[string]string{{"key": "private:office", "value": "100"}}},
+			want:      map[string]any{"distribution": map[string]loadZoneShare{"zone1": {LoadZone: "private:office", Percent: 100}}},
+		},
+		{
+			name:      "unknown zone",
+			runConfig: K6LoadTestRunConfig{LoadZones: []map[string]string{{"key": "amazon:jp:tokyo", "value": "100"}}},
+			available: available,
+			wantErr:   "unknown load zone 'amazon:jp:tokyo'",
+		},
+		{
+			name:      "shares must add up to 100",
+			runConfig: K6LoadTestRunConfig{LoadZones: []map[string]string{{"key": "amazon:us:ashburn", "value": "60"}, {"key": "amazon:de:frankfurt", "value": "30"}}},
+			available: available,
+			wantErr:   "the shares of the load zones add up to 90% instead of 100%",
+		},
+		{
+			name:      "invalid share",
+			runConfig: K6LoadTestRunConfig{LoadZones: []map[string]string{{"key": "amazon:us:ashburn", "value": "half"}}},
+			wantErr:   "invalid share 'half' of load zone 'amazon:us:ashburn', expected a percentage between 1 and 100",
+		},
+		{
+			name:      "negative project",
+			runConfig: K6LoadTestRunConfig{ProjectId: -1},
+			wantErr:   "the project id must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cloudOptions(tt.runConfig, tt.available)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_writeCloudWrapper(t *testing.T) {
+	script := writeFile(t, "test.js", []byte("export default function() {}"))
+
+	wrapper, err := writeCloudWrapper("", script, map[string]any{"projectID": 42, "name": "checkout"})
+
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Dir(script), ".steadybit-cloud-test.js"), wrapper)
+	content, err := os.ReadFile(wrapper)
+	require.NoError(t, err)
+	assert.Equal(t, `import * as script from "./test.js";
+export * from "./test.js";
+export default script.default;
+export const options = Object.assign({}, script.options, {
+  cloud: Object.assign({}, (script.options || {}).cloud, {"name":"checkout","projectID":42}),
+});
+`, string(content))
+}
+
+func TestCloudPrepareOverridesCloudOptions(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/load_zones",
+		httpmock.NewStringResponder(200, `{"value": [{"name": "Ashburn", "k6_load_zone_id": "amazon:us:ashburn", "public": true}]}`))
+	script := writeFile(t, "test.js", []byte("export default function() {}"))
+	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{
+			"file":      script,
+			"projectId": 42,
+			"loadZones": []map[string]string{{"key": "amazon:us:ashburn", "value": "100"}},
+		},
+		ExecutionId: newExecution(t),
+	})
+	action := k6LoadTestCloudAction{}
+	state := action.NewEmptyState()
+
+	result, err := action.Prepare(context.TODO(), &state, request)
+
+	require.NoError(t, err)
+	require.Nil(t, result)
+	assert.Equal(t, []string{"k6", "cloud", "run", filepath.Join(filepath.Dir(script), ".steadybit-cloud-test.js")}, state.Command[:4])
+	content, err := os.ReadFile(state.Command[3])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `{"distribution":{"zone1":{"loadZone":"amazon:us:ashburn","percent":100}},"projectID":42}`)
+}
+
+func TestCloudPrepareOverridesCloudOptionsOfBundle(t *testing.T) {
+	withCloudCredential(t)
+	file := writeFile(t, "bundle.zip", zipBundle(t, map[string]string{
+		"tests/main.js":      "import { check } from './lib/check.js';\nexport default function() { check(); }",
+		"tests/lib/check.js": "export function check() {}",
+		"README.md":          "load tests",
+	}))
+	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": file, "entrypoint": "tests/main.js", "testName": "checkout"},
+		ExecutionId: newExecution(t),
+	})
+	action := k6LoadTestCloudAction{}
+	state := action.NewEmptyState()
+
+	result, err := action.Prepare(context.TODO(), &state, request)
+
+	require.NoError(t, err)
+	require.Nil(t, result)
+	wrapper := filepath.Join("tests", ".steadybit-cloud-main.js")
+	assert.Equal(t, []string{"k6", "cloud", "run", wrapper}, state.Command[:4])
+	// k6 runs in the working dir, resolving the wrapper and its import from there
+	content, err := os.ReadFile(filepath.Join(state.WorkingDir, wrapper))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `import * as script from "./main.js";`)
+	assert.FileExists(t, filepath.Join(state.WorkingDir, "tests", "main.js"))
+	assert.NoFileExists(t, wrapper)
+}
+
+func TestCloudPrepareRejectsUnknownLoadZone(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/load_zones",
+		httpmock.NewStringResponder(200, `{"value": [{"name": "Ashburn", "k6_load_zone_id": "amazon:us:ashburn", "public": true}]}`))
+	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{
+			"file":      "test.js",
+			"loadZones": []map[string]string{{"key": "amazon:jp:tokyo", "value": "100"}},
+		},
+	})
+	action := k6LoadTestCloudAction{}
+	state := action.NewEmptyState()
+
+	result, err := action.Prepare(context.TODO(), &state, request)
+
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "Invalid cloud options: unknown load zone 'amazon:jp:tokyo'.", result.Error.Title)
+}
+
+func TestCloudPrepareRejectsCloudOptionsForArchives(t *testing.T) {
+	withCloudCredential(t)
+	file := writeFile(t, "archive.tar", tarBundle(t, map[string]string{"metadata.json": "{}"}))
+	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": file, "testName": "checkout"},
+		ExecutionId: newExecution(t),
+	})
+	action := k6LoadTestCloudAction{}
+	state := action.NewEmptyState()
+
+	result, err := action.Prepare(context.TODO(), &state, request)
+
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Contains(t, result.Error.Title, "cannot be overridden for k6 archives")
+}
+
+func TestCloudDescribeOffersLoadZones(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/load_zones",
+		httpmock.NewStringResponder(200, `{"value": [{"name": "Ashburn", "k6_load_zone_id": "amazon:us:ashburn"}, {"k6_load_zone_id": "amazon:de:frankfurt"}]}`))
+	action := &k6LoadTestCloudAction{}
+
+	description := action.Describe()
+
+	assert.Subset(t, parameterNames(description), []string{"credential", "projectId", "testName", "loadZones"})
+	// the load zones are fetched in the background, so that describing doesn't block
+	assert.Nil(t, loadZonesParameter(description).Options)
+	require.Eventually(t, func() bool { return loadZonesParameter(action.Describe()).Options != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []action_kit_api.ParameterOption{
+		action_kit_api.ExplicitParameterOption{Label: "Ashburn (amazon:us:ashburn)", Value: "amazon:us:ashburn"},
+		action_kit_api.ExplicitParameterOption{Label: "amazon:de:frankfurt", Value: "amazon:de:frankfurt"},
+	}, *loadZonesParameter(action.Describe()).Options)
+}
+
+func TestCloudDescribeDoesNotBlockOnUnreachableApi(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/load_zones", func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		<-release
+		return httpmock.NewStringResponse(404, ""), nil
+	})
+	defer close(release)
+	action := &k6LoadTestCloudAction{}
+
+	begin := time.Now()
+	action.Describe()
+	action.Describe()
+
+	assert.Less(t, time.Since(begin), time.Second)
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load(), "the load zones must be fetched only once at a time")
+}
+
+func loadZonesParameter(description action_kit_api.ActionDescription) action_kit_api.ActionParameter {
+	for _, p := range description.Parameters {
+		if p.Name == "loadZones" {
+			return p
+		}
+	}
+	return action_kit_api.ActionParameter{}
+}
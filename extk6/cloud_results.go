@@ -0,0 +1,153 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/k6cloud"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// cloudResults are the results of a cloud test run, written to the cloud results artifact.
+type cloudResults struct {
+	Url        string              `json:"url,omitempty"`
+	TestRun    *k6cloud.TestRun    `json:"testRun,omitempty"`
+	Thresholds []k6cloud.Threshold `json:"thresholds"`
+	Checks     []k6cloud.Check     `json:"checks"`
+	HttpUrls   []k6cloud.HttpUrl   `json:"httpUrls"`
+}
+
+func cloudResultsFilename(state *K6LoadTestRunState) string {
+	return fmt.Sprintf("/tmp/steadybit/%v/k6_cloud_results.json", state.ExecutionId) //Folder is managed by action_kit_sdk's file download handling
+}
+
+// fetchCloudResults downloads the results of the cloud test run. Results which
+// cannot be fetched are reported as warnings, the others are returned anyway.
+func fetchCloudResults(ctx context.Context, client *k6cloud.Client, state *K6LoadTestRunState) (*cloudResults, []action_kit_api.Message) {
+	results := &cloudResults{Url: state.CloudRunUrl}
+	var warnings []action_kit_api.Message
+	warn := func(what string, err error) {
+		log.Warn().Err(err).Msgf("Failed to get the %s of K6 cloud test run %s.", what, state.CloudRunId)
+		warnings = append(warnings, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Warn),
+			Message: fmt.Sprintf("Failed to get the %s of the K6 cloud test run %s: %s", what, state.CloudRunId, err.Error()),
+		})
+	}
+
+	var err error
+	if results.TestRun, err = client.GetTestRun(ctx, state.CloudRunId); err != nil {
+		warn("status", err)
+	}
+	if results.Thresholds, err = client.GetThresholds(ctx, state.CloudRunId); err != nil {
+		warn("thresholds", err)
+	}
+	if results.Checks, err = client.GetChecks(ctx, state.CloudRunId); err != nil {
+		warn("checks", err)
+	}
+	if results.HttpUrls, err = client.GetHttpUrls(ctx, state.CloudRunId); err != nil {
+		warn("HTTP metrics", err)
+	}
+	return results, warnings
+}
+
+func writeCloudResults(path string, results *cloudResults) error {
+	content, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+func cloudResultsToMessages(results *cloudResults) []action_kit_api.Message {
+	messages := make([]action_kit_api.Message, 0)
+	if results.Url != "" {
+		messages = append(messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: fmt.Sprintf("K6 cloud test run: %s", results.Url),
+			Fields:  extutil.Ptr(action_kit_api.MessageFields{"url": results.Url}),
+		})
+	}
+
+	for _, threshold := range results.Thresholds {
+		level, outcome := action_kit_api.Info, "passed"
+		if threshold.Tainted {
+			level, outcome = action_kit_api.Error, "failed"
+		}
+		message := fmt.Sprintf("Threshold %s %s", threshold.Name, outcome)
+		if threshold.CalculatedValue != nil {
+			message = fmt.Sprintf("%s: %s", message, formatValue(*threshold.CalculatedValue))
+		}
+		messages = append(messages, action_kit_api.Message{
+			Level:   extutil.Ptr(level),
+			Message: message,
+			Fields:  extutil.Ptr(action_kit_api.MessageFields{"threshold": threshold.Name, "result": outcome}),
+		})
+	}
+
+	for _, check := range results.Checks {
+		summary := check.MetricSummary
+		level := action_kit_api.Info
+		if summary.FailCount > 0 {
+			level = action_kit_api.Warn
+		}
+		messages = append(messages, action_kit_api.Message{
+			Level: extutil.Ptr(level),
+			Message: fmt.Sprintf("Check '%s': %s%% succeeded (%d passed, %d failed)",
+				check.Name, formatValue(summary.SuccessRate*100), summary.SuccessCount, summary.FailCount),
+			Fields: extutil.Ptr(action_kit_api.MessageFields{
+				"check":   check.Name,
+				"passes":  strconv.FormatInt(summary.SuccessCount, 10),
+				"fails":   strconv.FormatInt(summary.FailCount, 10),
+				"success": formatValue(summary.SuccessRate),
+			}),
+		})
+	}
+
+	for i, url := range results.HttpUrls {
+		if i == maxSummaryMessages {
+			messages = append(messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("%d more URLs are contained in the cloud results artifact.", len(results.HttpUrls)-maxSummaryMessages),
+			})
+			break
+		}
+		summary := url.HttpMetricSummary
+		fields := action_kit_api.MessageFields{
+			"method":   url.Method,
+			"url":      url.Name,
+			"status":   strconv.Itoa(url.Status),
+			"scenario": url.Scenario,
+			"requests": strconv.FormatInt(summary.RequestsCount, 10),
+		}
+		message := fmt.Sprintf("%s %s %d (%s): %d requests", url.Method, url.Name, url.Status, url.Scenario, summary.RequestsCount)
+		for _, stat := range []struct {
+			name  string
+			value *float64
+		}{
+			{"avg", summary.Duration.Mean},
+			{"p(95)", summary.Duration.P95},
+			{"p(99)", summary.Duration.P99},
+			{"max", summary.Duration.Max},
+		} {
+			if stat.value != nil {
+				message = fmt.Sprintf("%s %s=%sms", message, stat.name, formatValue(*stat.value))
+				fields[stat.name] = formatValue(*stat.value)
+			}
+		}
+		messages = append(messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: message,
+			Fields:  &fields,
+		})
+	}
+	return messages
+}
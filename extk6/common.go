@@ -2,16 +2,22 @@ package extk6
 
 import (
 	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-k6/k6cloud"
 	extension_kit "github.com/steadybit/extension-kit"
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extcmd"
@@ -34,11 +40,46 @@ type K6LoadTestRunState struct {
 	CmdStateID  string    `json:"cmdStateId"`
 	ExecutionId uuid.UUID `json:"executionId"`
 	CloudRunId  string    `json:"cloudRunId"`
+	ApiAddress  string    `json:"apiAddress"`
+	// CloudRunUrl links to the cloud test run in the k6 app.
+	CloudRunUrl string `json:"cloudRunUrl"`
+	// CloudRunStatus is the last reported status of the cloud test run.
+	CloudRunStatus string `json:"cloudRunStatus"`
+	// Outputs are the names of the configured outputs k6 streams the results to, their settings are resolved when starting.
+	Outputs []string `json:"outputs"`
+	// Vus are the VUs the run is admitted with, see admission.
+	Vus int `json:"vus"`
+	// QueuedAt is set while the run waits for admission.
+	QueuedAt *time.Time `json:"queuedAt"`
+	// CloudCredential is the name of the credential for the cloud api, its token is resolved when needed.
+	CloudCredential string `json:"cloudCredential"`
+	// WorkingDir is the directory k6 is started in, set for extracted script bundles.
+	WorkingDir string `json:"workingDir"`
+	// SecretEnvironmentKeys are the keys of environment variables whose values must not be revealed.
+	SecretEnvironmentKeys []string `json:"secretEnvironmentKeys"`
+	// StopGracePeriod is how long k6 may take to shut down after being interrupted before it is killed.
+	StopGracePeriod time.Duration `json:"stopGracePeriod"`
+	// ThresholdsReported is set once the failed thresholds have been reported.
+	ThresholdsReported bool `json:"thresholdsReported"`
+	// AbortedOnFail is set once k6 logged that a threshold with abortOnFail stopped the test.
+	AbortedOnFail bool `json:"abortedOnFail"`
 }
 
 type K6LoadTestRunConfig struct {
-	Environment []map[string]string
-	File        string
+	Environment       []map[string]string
+	SecretEnvironment []map[string]string
+	File              string
+	Entrypoint        string
+	Vus               int
+	TestDuration      int
+	Iterations        int
+	Stages            []map[string]string
+	StopGracePeriod   *int
+	ProjectId         int
+	TestName          string
+	LoadZones         []map[string]string
+	Credential        string
+	Outputs           *[]string
 }
 
 func getActionDescription(actionId string, label string, description string, hint *action_kit_api.ActionHint) *action_kit_api.ActionDescription {
@@ -61,16 +102,80 @@ func getActionDescription(actionId string, label string, description string, hin
 				Required:    new(true),
 				AcceptedFileTypes: new([]string{
 					".js",
+					".zip",
+					".tar",
+					".tar.gz",
+					".tgz",
 				}),
 				Order: new(1),
 			},
+			{
+				Name:        "entrypoint",
+				Label:       "Entrypoint",
+				Description: new("Path of the script to run within an uploaded zip or tar bundle. Defaults to script.js or main.js."),
+				Type:        action_kit_api.ActionParameterTypeString,
+				Required:    new(false),
+				Advanced:    new(true),
+				Order:       new(2),
+			},
 			{
 				Name:        "environment",
 				Label:       "Environment variables",
 				Description: new("Environment variables which will be accessible in your k6 script by ${__ENV.foobar}"),
 				Type:        action_kit_api.ActionParameterTypeKeyValue,
 				Required:    new(false),
-				Order:       new(2),
+				Order:       new(3),
+			},
+			{
+				Name:        "secretEnvironment",
+				Label:       "Secret environment variables",
+				Description: new("Environment variables like ${__ENV.foobar}, whose values are masked in logs and messages."),
+				Type:        action_kit_api.ActionParameterTypeKeyValue,
+				Required:    new(false),
+				Order:       new(4),
+			},
+			{
+				Name:        "vus",
+				Label:       "Virtual users",
+				Description: new("Number of virtual users, overrides the script's options."),
+				Type:        action_kit_api.ActionParameterTypeInteger,
+				Required:    new(false),
+				MinValue:    new(1),
+				Order:       new(5),
+			},
+			{
+				Name:        "testDuration",
+				Label:       "Test duration",
+				Description: new("Duration of the load test, overrides the script's options. Cannot be combined with stages."),
+				Type:        action_kit_api.ActionParameterTypeDuration,
+				Required:    new(false),
+				Order:       new(6),
+			},
+			{
+				Name:        "iterations",
+				Label:       "Iterations",
+				Description: new("Total number of script iterations shared by all virtual users, overrides the script's options. Cannot be combined with stages."),
+				Type:        action_kit_api.ActionParameterTypeInteger,
+				Required:    new(false),
+				MinValue:    new(1),
+				Order:       new(7),
+			},
+			{
+				Name:        "stages",
+				Label:       "Stages",
+				Description: new("Ramp the number of virtual users up or down in stages, overriding the script's options. Use the stage's duration (e.g. 30s) as key and the target number of virtual users as value."),
+				Type:        action_kit_api.ActionParameterTypeKeyValue,
+				Required:    new(false),
+				Order:       new(8),
+			},
+			{
+				Name:        "stopGracePeriod",
+				Label:       "Stop grace period",
+				Description: new("How long k6 may take to run teardown() and flush its results when the load test is stopped early, before it is killed. Defaults to the extension's configuration."),
+				Type:        action_kit_api.ActionParameterTypeDuration,
+				Required:    new(false),
+				Advanced:    new(true),
+				Order:       new(9),
 			},
 		},
 		Status: new(action_kit_api.MutatingEndpointReferenceWithCallInterval{
@@ -80,41 +185,211 @@ func getActionDescription(actionId string, label string, description string, hin
 	}
 }
 
+// prepareScript resolves the script to run from the uploaded file, extracting
+// bundles into the execution's working directory.
+func prepareScript(state *K6LoadTestRunState, request action_kit_api.PrepareActionRequestBody, runConfig K6LoadTestRunConfig) (string, error) {
+	script, workDir, err := resolveScript(request.ExecutionId, runConfig.File, runConfig.Entrypoint)
+	if err != nil {
+		return "", extension_kit.ToError("Failed to prepare the k6 script.", err)
+	}
+	state.WorkingDir = workDir
+	return script, nil
+}
+
 func prepare(state *K6LoadTestRunState, request action_kit_api.PrepareActionRequestBody, command []string) (*action_kit_api.PrepareResult, error) {
-	var config K6LoadTestRunConfig
-	if err := extconversion.Convert(request.Config, &config); err != nil {
+	var runConfig K6LoadTestRunConfig
+	if err := extconversion.Convert(request.Config, &runConfig); err != nil {
 		return nil, extension_kit.ToError("Failed to unmarshal the config.", err)
 	}
 
 	state.ExecutionId = request.ExecutionId
 	state.Command = command
+	state.StopGracePeriod = config.Config.StopGracePeriod
+	if runConfig.StopGracePeriod != nil {
+		state.StopGracePeriod = time.Duration(*runConfig.StopGracePeriod) * time.Millisecond
+	}
 
-	if config.Environment != nil {
-		for _, value := range config.Environment {
+	loadShape, err := loadShapeArgs(runConfig)
+	if err != nil {
+		return &action_kit_api.PrepareResult{
+			Error: &action_kit_api.ActionKitError{
+				Status: extutil.Ptr(action_kit_api.Errored),
+				Title:  fmt.Sprintf("Invalid load shape: %s.", err),
+			},
+		}, nil
+	}
+	state.Command = append(state.Command, executionTags(request)...)
+	state.Command = append(state.Command, loadShape...)
+	// added before the user's variables, which may override them
+	state.Command = append(state.Command, executionEnv(request)...)
+
+	if runConfig.Environment != nil {
+		for _, value := range runConfig.Environment {
 			state.Command = append(state.Command, "--env")
 			state.Command = append(state.Command, fmt.Sprintf("%s=%s", value["key"], value["value"]))
 		}
 	}
+	// kept out of the state, they are added to the command when k6 is started
+	var secretArgs []string
+	for _, value := range runConfig.SecretEnvironment {
+		secretArgs = append(secretArgs, "--env", fmt.Sprintf("%s=%s", value["key"], value["value"]))
+		state.SecretEnvironmentKeys = append(state.SecretEnvironmentKeys, value["key"])
+	}
+	if len(secretArgs) > 0 {
+		secretEnvironments.Store(state.ExecutionId.String(), secretArgs)
+	}
 
 	return nil, nil
 }
 
-func start(state *K6LoadTestRunState, token string) (*action_kit_api.StartResult, error) {
-	log.Info().Msgf("Starting k6 load test with command: %s", strings.Join(state.Command, " "))
-	cmd := exec.Command(state.Command[0], state.Command[1:]...)
-	cmd.Env = os.Environ()
-	if token != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("K6_CLOUD_TOKEN=%s", token))
+// executionTags tags all metrics with the experiment execution, so that they can
+// be filtered in the outputs.
+func executionTags(request action_kit_api.PrepareActionRequestBody) []string {
+	var args []string
+	tag := func(name, value string) {
+		args = append(args, "--tag", fmt.Sprintf("%s=%s", name, value))
+	}
+	if ctx := request.ExecutionContext; ctx != nil {
+		if ctx.ExecutionId != nil {
+			tag("steadybit_execution_id", strconv.Itoa(*ctx.ExecutionId))
+		}
+		if ctx.ExperimentKey != nil {
+			tag("steadybit_experiment_key", *ctx.ExperimentKey)
+		}
+	}
+	if request.ExecutionId != uuid.Nil {
+		tag("steadybit_step_id", request.ExecutionId.String())
+	}
+	if request.Target != nil && request.Target.Name != "" {
+		tag("steadybit_location", request.Target.Name)
+	}
+	return args
+}
+
+// executionEnv passes the experiment execution to the script as STEADYBIT_
+// environment variables, including the attributes of the selected location.
+func executionEnv(request action_kit_api.PrepareActionRequestBody) []string {
+	var args []string
+	env := func(name, value string) {
+		args = append(args, "--env", fmt.Sprintf("STEADYBIT_%s=%s", name, value))
 	}
+	if ctx := request.ExecutionContext; ctx != nil {
+		if ctx.ExecutionId != nil {
+			env("EXECUTION_ID", strconv.Itoa(*ctx.ExecutionId))
+		}
+		if ctx.ExecutionUri != nil {
+			env("EXECUTION_URI", *ctx.ExecutionUri)
+		}
+		if ctx.ExperimentKey != nil {
+			env("EXPERIMENT_KEY", *ctx.ExperimentKey)
+		}
+		if ctx.ExperimentUri != nil {
+			env("EXPERIMENT_URI", *ctx.ExperimentUri)
+		}
+		if ctx.AgentPid != nil {
+			env("AGENT_PID", strconv.Itoa(*ctx.AgentPid))
+		}
+	}
+	if request.ExecutionId != uuid.Nil {
+		env("STEP_ID", request.ExecutionId.String())
+	}
+	if request.Target != nil {
+		if request.Target.Name != "" {
+			env("LOCATION", request.Target.Name)
+		}
+		keys := make([]string, 0, len(request.Target.Attributes))
+		for key := range request.Target.Attributes {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			env("TARGET_"+envName(key), strings.Join(request.Target.Attributes[key], ","))
+		}
+	}
+	return args
+}
+
+// envName turns an attribute like k8s.pod.name into K8S_POD_NAME.
+func envName(attribute string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, attribute))
+}
+
+// loadShapeArgs translates the load shape parameters into k6 flags, rejecting
+// combinations k6 would refuse.
+func loadShapeArgs(runConfig K6LoadTestRunConfig) ([]string, error) {
+	if runConfig.Vus < 0 || runConfig.TestDuration < 0 || runConfig.Iterations < 0 {
+		return nil, errors.New("VUs, duration and iterations must not be negative")
+	}
+	if len(runConfig.Stages) > 0 && (runConfig.TestDuration > 0 || runConfig.Iterations > 0) {
+		return nil, errors.New("stages cannot be combined with a duration or iterations")
+	}
+	if runConfig.Iterations > 0 && runConfig.Vus > runConfig.Iterations {
+		return nil, fmt.Errorf("the number of VUs (%d) cannot exceed the number of iterations (%d)", runConfig.Vus, runConfig.Iterations)
+	}
+
+	var args []string
+	if runConfig.Vus > 0 {
+		args = append(args, "--vus", strconv.Itoa(runConfig.Vus))
+	}
+	if runConfig.TestDuration > 0 {
+		args = append(args, "--duration", (time.Duration(runConfig.TestDuration) * time.Millisecond).String())
+	}
+	if runConfig.Iterations > 0 {
+		args = append(args, "--iterations", strconv.Itoa(runConfig.Iterations))
+	}
+	for _, stage := range runConfig.Stages {
+		duration, err := time.ParseDuration(stage["key"])
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid stage duration '%s', expected a duration like 30s or 1m30s", stage["key"])
+		}
+		target, err := strconv.Atoi(stage["value"])
+		if err != nil || target < 0 {
+			return nil, fmt.Errorf("invalid stage target '%s', expected a number of VUs", stage["value"])
+		}
+		args = append(args, "--stage", fmt.Sprintf("%s:%d", duration, target))
+	}
+	return args, nil
+}
+
+// start starts k6 with the given environment variables in addition to the extension's.
+func start(state *K6LoadTestRunState, env []string) (*action_kit_api.StartResult, error) {
+	executionId := state.ExecutionId.String()
+	secretArgs, err := takeSecretEnvArgs(state)
+	if err != nil {
+		admissions.release(executionId)
+		return nil, extension_kit.ToError("Failed to pass the secret environment variables to k6.", err)
+	}
+	command := slices.Concat(state.Command, secretArgs)
+	log.Info().Msgf("Starting k6 load test with command: %s", strings.Join(redactCommand(command, state.SecretEnvironmentKeys), " "))
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = state.WorkingDir
+	cmd.Env = append(scrubbedEnv(os.Environ(), config.Config.K6EnvAllowList), env...)
 	cmdState := extcmd.NewCmdState(cmd)
 	state.CmdStateID = cmdState.Id
-	err := cmd.Start()
+	redactors.Store(cmdState.Id, newRedactor(command, state.SecretEnvironmentKeys))
+	isolated := isolate(cmd, executionId)
+	err = cmd.Start()
 	if err != nil {
+		isolated.cleanup()
+		redactors.Delete(cmdState.Id)
+		admissions.release(executionId)
 		return nil, extension_kit.ToError("Failed to start command.", err)
 	}
 
 	state.Pid = cmd.Process.Pid
+	isolated.started(state.Pid)
+	isolations.Store(cmdState.Id, isolated)
+	done := registerExitChannel(cmdState.Id)
+	registerRunningTest(cmdState.Id, state.ApiAddress)
 	go func() {
+		defer close(done)
+		defer unregisterRunningTest(cmdState.Id)
+		defer admissions.release(executionId)
 		cmdErr := cmdState.Wait()
 		if cmdErr != nil {
 			log.Warn().Msgf("Failed to execute k6: %s", cmdErr)
@@ -123,6 +398,17 @@ func start(state *K6LoadTestRunState, token string) (*action_kit_api.StartResult
 	log.Info().Msgf("Started load test.")
 
 	state.Command = nil
+	if isolated.memoryUnlimited() {
+		log.Warn().Msg("The memory limit of k6 is not enforced, as the extension cannot create cgroups.")
+		return &action_kit_api.StartResult{
+			Messages: &[]action_kit_api.Message{
+				{
+					Level:   extutil.Ptr(action_kit_api.Warn),
+					Message: "The memory limit of the load test is not enforced, as the extension cannot create cgroups.",
+				},
+			},
+		}, nil
+	}
 	return nil, nil
 }
 
@@ -138,40 +424,33 @@ func status(state *K6LoadTestRunState) (*action_kit_api.StatusResult, error) {
 
 	// check if k6 is still running
 	exitCode := cmdState.ExitCode()
-	stdOut := cmdState.GetLines(false)
+	stdOut := getRedactor(state.CmdStateID).redactLines(cmdState.GetLines(false))
 	addCloudRunIdToState(stdOut, state)
+	if containsLine(stdOut, abortOnFailLog) {
+		state.AbortedOnFail = true
+	}
 	stdOutToLog(stdOut)
+	var failedThresholds []thresholdFailure
 	if exitCode == -1 {
 		log.Debug().Msgf("K6 is still running")
 		result.Completed = false
 	} else if exitCode == 0 {
 		log.Info().Msgf("K6 run completed successfully")
 		result.Completed = true
-	} else if exitCode == 97 || exitCode == 99 {
-		log.Info().Msgf("K6 run completed with threshold failures")
-		result.Completed = true
-		result.Error = &action_kit_api.ActionKitError{
-			Status: extutil.Ptr(action_kit_api.Failed),
-			Title:  "Some thresholds have failed.",
-		}
 	} else {
-		title := fmt.Sprintf("K6 run failed, exit-code %d", exitCode)
-		stdOutError := extractErrorFromStdOut(stdOut)
-		if stdOutError != nil {
-			title = *stdOutError
+		if isThresholdExitCode(exitCode) {
+			failedThresholds = reportFailedThresholds(state)
 		}
 		result.Completed = true
-		result.Error = &action_kit_api.ActionKitError{
-			Status: extutil.Ptr(action_kit_api.Errored),
-			Title:  title,
-		}
+		result.Error = exitCodeError(exitCode, state.AbortedOnFail, stdOut, failedThresholds)
+		log.Info().Msgf("K6 run failed with exit code %d: %s", exitCode, result.Error.Title)
 	}
 
 	filename := fmt.Sprintf("/tmp/steadybit/%v/k6_log.txt", state.ExecutionId) //Folder is managed by action_kit_sdk's file download handling
 	if err := extfile.AppendToFile(filename, stdOut); err != nil {
 		return nil, extension_kit.ToError("Failed to append log to file", err)
 	}
-	messages := stdOutToMessages(stdOut)
+	messages := append(stdOutToMessages(stdOut), thresholdFailureMessages(failedThresholds)...)
 	log.Debug().Msgf("Returning %d messages", len(messages))
 
 	result.Messages = new(messages)
@@ -180,27 +459,14 @@ func status(state *K6LoadTestRunState) (*action_kit_api.StatusResult, error) {
 
 func addCloudRunIdToState(lines []string, state *K6LoadTestRunState) {
 	for _, line := range lines {
-		trimmed := strings.TrimSpace(strings.ReplaceAll(line, "\n", ""))
-		cloudRunId := substringAfter(trimmed, "output: https://app.k6.io/runs/")
-		if cloudRunId != nil {
-			log.Info().Msgf("Found cloud run id: %s", *cloudRunId)
-			state.CloudRunId = *cloudRunId
+		if cloudRunId, cloudRunUrl, found := k6cloud.ParseRunUrl(line); found && cloudRunId != state.CloudRunId {
+			log.Info().Msgf("Found cloud run id: %s", cloudRunId)
+			state.CloudRunId = cloudRunId
+			state.CloudRunUrl = cloudRunUrl
 		}
 	}
 }
 
-func substringAfter(value string, a string) *string {
-	pos := strings.LastIndex(value, a)
-	if pos == -1 {
-		return nil
-	}
-	adjustedPos := pos + len(a)
-	if adjustedPos >= len(value) {
-		return nil
-	}
-	return new(value[adjustedPos:])
-}
-
 func stdOutToLog(lines []string) {
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(strings.ReplaceAll(line, "\n", ""))
@@ -214,12 +480,22 @@ func stdOutToMessages(lines []string) []action_kit_api.Message {
 	messages := make([]action_kit_api.Message, 0)
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(strings.ReplaceAll(line, "\n", ""))
-		if len(trimmed) > 0 {
-			messages = append(messages, action_kit_api.Message{
-				Level:   extutil.Ptr(action_kit_api.Info),
-				Message: trimmed,
-			})
+		if len(trimmed) == 0 {
+			continue
+		}
+		parsed := parseLogLine(trimmed)
+		message := action_kit_api.Message{
+			Level:     extutil.Ptr(parsed.level),
+			Message:   parsed.message,
+			Timestamp: parsed.timestamp,
 		}
+		if message.Message == "" {
+			message.Message = trimmed
+		}
+		if len(parsed.fields) > 0 {
+			message.Fields = extutil.Ptr(action_kit_api.MessageFields(parsed.fields))
+		}
+		messages = append(messages, message)
 	}
 	return messages
 }
@@ -227,11 +503,9 @@ func stdOutToMessages(lines []string) []action_kit_api.Message {
 func extractErrorFromStdOut(lines []string) *string {
 	//Find error, last log lines first
 	for i := len(lines) - 1; i >= 0; i-- {
-		if strings.Contains(lines[i], "level=error") {
-			split := strings.SplitAfter(lines[i], "msg=")
-			if len(split) > 1 {
-				return &split[1]
-			}
+		parsed := parseLogLine(strings.TrimSpace(lines[i]))
+		if parsed.level == action_kit_api.Error && parsed.message != "" {
+			return &parsed.message
 		}
 	}
 	return nil
@@ -240,6 +514,8 @@ func extractErrorFromStdOut(lines []string) *string {
 func stop(state *K6LoadTestRunState) (*action_kit_api.StopResult, error) {
 	if state.CmdStateID == "" {
 		log.Info().Msg("K6 not yet started, nothing to stop.")
+		secretEnvironments.Delete(state.ExecutionId.String())
+		admissions.dequeue(state.ExecutionId.String())
 		return nil, nil
 	}
 
@@ -248,34 +524,53 @@ func stop(state *K6LoadTestRunState) (*action_kit_api.StopResult, error) {
 		return nil, extension_kit.ToError("Failed to find command state", err)
 	}
 	extcmd.RemoveCmdState(state.CmdStateID)
+	defer redactors.Delete(state.CmdStateID)
 
-	// kill k6 if it is still running
-	var pid = state.Pid
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return nil, extension_kit.ToError("Failed to find process", err)
-	}
-	_ = process.Kill()
+	// interrupt k6 if it is still running, so that teardown and outputs are not skipped
+	interrupted := !awaitExit(state.CmdStateID, 0)
+	leftovers := terminateProcess(state.CmdStateID, state.Pid, state.StopGracePeriod)
+	releaseIsolation(state.CmdStateID)
 
 	// read Stout and Stderr and send it as Messages
-	stdOut := cmdState.GetLines(true)
+	stdOut := getRedactor(state.CmdStateID).redactLines(cmdState.GetLines(true))
 	stdOutToLog(stdOut)
 	filename := fmt.Sprintf("/tmp/steadybit/%v/k6_log.txt", state.ExecutionId) //Folder is managed by action_kit_sdk's file download handling
 	if err := extfile.AppendToFile(filename, stdOut); err != nil {
 		return nil, extension_kit.ToError("Failed to append log to file", err)
 	}
 	messages := stdOutToMessages(stdOut)
+	if len(leftovers) > 0 {
+		messages = append(messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Warn),
+			Message: fmt.Sprintf("K6 left %d processes behind, which were killed: %s", len(leftovers), formatGroupProcesses(leftovers)),
+		})
+	}
 
 	// read return code and send it as Message
 	exitCode := cmdState.ExitCode()
-	if exitCode != 0 && exitCode != -1 {
+	// k6 exits with 105 when interrupted, which is expected if the extension stopped it early
+	if exitCode != 0 && exitCode != -1 && !(interrupted && exitCode == exitCodeExternalAbort) {
 		messages = append(messages, action_kit_api.Message{
 			Level:   extutil.Ptr(action_kit_api.Error),
 			Message: fmt.Sprintf("K6 run failed with exit code %d", exitCode),
 		})
 	}
+	if isThresholdExitCode(exitCode) {
+		messages = append(messages, thresholdFailureMessages(reportFailedThresholds(state))...)
+	}
 
 	metricsFilename := fmt.Sprintf("/tmp/steadybit/%v/metrics.json", state.ExecutionId)
+	summaryFilename := fmt.Sprintf("/tmp/steadybit/%v/summary.json", state.ExecutionId)
+
+	summaries, err := summarizeJsonOutput(metricsFilename)
+	if err == nil {
+		messages = append(messages, summaryToMessages(summaries)...)
+		if err := writeSummary(summaryFilename, summaries); err != nil {
+			return nil, extension_kit.ToError("Failed to write summary", err)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Warn().Err(err).Msg("Failed to summarize k6 metrics.")
+	}
 
 	artifacts := make([]action_kit_api.Artifact, 0)
 	if artifacts, err = appendFileArtifact(artifacts, filename, "$(experimentKey)_$(executionId)_k6_log.txt"); err != nil {
@@ -284,6 +579,12 @@ func stop(state *K6LoadTestRunState) (*action_kit_api.StopResult, error) {
 	if artifacts, err = appendFileArtifact(artifacts, metricsFilename, "$(experimentKey)_$(executionId)_k6_metrics.json"); err != nil {
 		return nil, err
 	}
+	if artifacts, err = appendFileArtifact(artifacts, summaryFilename, "$(experimentKey)_$(executionId)_k6_summary.json"); err != nil {
+		return nil, err
+	}
+	if artifacts, err = appendFileArtifact(artifacts, summaryExportFilename(state.ExecutionId), "$(experimentKey)_$(executionId)_k6_summary_export.json"); err != nil {
+		return nil, err
+	}
 
 	log.Debug().Msgf("Returning %d messages", len(messages))
 	return &action_kit_api.StopResult{
@@ -3,27 +3,31 @@ package extk6
 import (
 	"reflect"
 	"testing"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-k6/k6cloud"
+	"github.com/stretchr/testify/assert"
 )
 
-func Test_substringAfter(t *testing.T) {
-	type args struct {
-		value string
-		after string
-	}
+func Test_addCloudRunIdToState(t *testing.T) {
 	tests := []struct {
-		name string
-		args args
-		want *string
+		name  string
+		lines []string
+		want  string
 	}{
-		{name: "match", args: args{value: "abc", after: "b"}, want: new("c")},
-		{name: "no match", args: args{value: "aaa", after: "b"}, want: nil},
-		{name: "no match after last", args: args{value: "abc", after: "c"}, want: nil},
-		{name: "empty", args: args{value: "", after: "b"}, want: nil},
+		{name: "legacy", lines: []string{"  execution: cloud\n", "     output: https://app.k6.io/runs/1234567\n"}, want: "1234567"},
+		{name: "grafana cloud", lines: []string{"     output: cloud (https://mystack.grafana.net/a/k6-app/runs/7654321)\n"}, want: "7654321"},
+		{name: "no match", lines: []string{"     output: json (metrics.json)\n"}, want: ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := substringAfter(tt.args.value, tt.args.after); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("substringAfter() = %v, want %v", got, tt.want)
+			state := &K6LoadTestRunState{}
+			addCloudRunIdToState(tt.lines, state)
+			if !reflect.DeepEqual(state.CloudRunId, tt.want) {
+				t.Errorf("addCloudRunIdToState() = %v, want %v", state.CloudRunId, tt.want)
 			}
 		})
 	}
@@ -49,3 +53,107 @@ func Test_extractErrorFromStdOut(t *testing.T) {
 		})
 	}
 }
+
+func Test_loadShapeArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  K6LoadTestRunConfig
+		want    []string
+		wantErr string
+	}{
+		{name: "none", config: K6LoadTestRunConfig{}, want: nil},
+		{name: "vus and duration", config: K6LoadTestRunConfig{Vus: 10, TestDuration: 90000}, want: []string{"--vus", "10", "--duration", "1m30s"}},
+		{name: "vus and iterations", config: K6LoadTestRunConfig{Vus: 2, Iterations: 100}, want: []string{"--vus", "2", "--iterations", "100"}},
+		{name: "stages", config: K6LoadTestRunConfig{Stages: []map[string]string{{"key": "30s", "value": "10"}, {"key": "1m", "value": "0"}}}, want: []string{"--stage", "30s:10", "--stage", "1m0s:0"}},
+		{name: "stages and duration", config: K6LoadTestRunConfig{TestDuration: 1000, Stages: []map[string]string{{"key": "30s", "value": "10"}}}, wantErr: "stages cannot be combined with a duration or iterations"},
+		{name: "stages and iterations", config: K6LoadTestRunConfig{Iterations: 10, Stages: []map[string]string{{"key": "30s", "value": "10"}}}, wantErr: "stages cannot be combined with a duration or iterations"},
+		{name: "more vus than iterations", config: K6LoadTestRunConfig{Vus: 10, Iterations: 5}, wantErr: "the number of VUs (10) cannot exceed the number of iterations (5)"},
+		{name: "negative", config: K6LoadTestRunConfig{Vus: -1}, wantErr: "VUs, duration and iterations must not be negative"},
+		{name: "invalid stage duration", config: K6LoadTestRunConfig{Stages: []map[string]string{{"key": "soon", "value": "10"}}}, wantErr: "invalid stage duration 'soon', expected a duration like 30s or 1m30s"},
+		{name: "invalid stage target", config: K6LoadTestRunConfig{Stages: []map[string]string{{"key": "30s", "value": "many"}}}, wantErr: "invalid stage target 'many', expected a number of VUs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadShapeArgs(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("loadShapeArgs() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("loadShapeArgs() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadShapeArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_executionTags(t *testing.T) {
+	stepId := uuid.MustParse("6f1c8a4e-3b1e-4d7a-9a57-0c7e4d3b2a10")
+
+	assert.Equal(t, []string{
+		"--tag", "steadybit_execution_id=42",
+		"--tag", "steadybit_experiment_key=ADM-1",
+		"--tag", "steadybit_step_id=6f1c8a4e-3b1e-4d7a-9a57-0c7e4d3b2a10",
+		"--tag", "steadybit_location=steadybit-agent-extension-k6-7d9f",
+	}, executionTags(action_kit_api.PrepareActionRequestBody{
+		ExecutionId:      stepId,
+		ExecutionContext: &action_kit_api.ExecutionContext{ExecutionId: new(42), ExperimentKey: new("ADM-1")},
+		Target:           &action_kit_api.Target{Name: "steadybit-agent-extension-k6-7d9f"},
+	}))
+	assert.Empty(t, executionTags(action_kit_api.PrepareActionRequestBody{}))
+}
+
+func Test_executionEnv(t *testing.T) {
+	request := action_kit_api.PrepareActionRequestBody{
+		ExecutionId: uuid.MustParse("6f1c8a4e-3b1e-4d7a-9a57-0c7e4d3b2a10"),
+		ExecutionContext: &action_kit_api.ExecutionContext{
+			ExecutionId:   new(42),
+			ExecutionUri:  new("https://platform.steadybit.com/experiments/ADM-1/executions/42"),
+			ExperimentKey: new("ADM-1"),
+		},
+		Target: &action_kit_api.Target{
+			Name: "steadybit-agent-extension-k6-7d9f",
+			Attributes: map[string][]string{
+				"k8s.pod.name":     {"extension-k6-7d9f"},
+				"k8s.cluster-name": {"prod"},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"--env", "STEADYBIT_EXECUTION_ID=42",
+		"--env", "STEADYBIT_EXECUTION_URI=https://platform.steadybit.com/experiments/ADM-1/executions/42",
+		"--env", "STEADYBIT_EXPERIMENT_KEY=ADM-1",
+		"--env", "STEADYBIT_STEP_ID=6f1c8a4e-3b1e-4d7a-9a57-0c7e4d3b2a10",
+		"--env", "STEADYBIT_LOCATION=steadybit-agent-extension-k6-7d9f",
+		"--env", "STEADYBIT_TARGET_K8S_CLUSTER_NAME=prod",
+		"--env", "STEADYBIT_TARGET_K8S_POD_NAME=extension-k6-7d9f",
+	}, executionEnv(request))
+}
+
+func TestParameterOrdersAreUnique(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	withOutputs(t, config.Outputs{"statsd": {Output: "statsd"}})
+	config.Config.EnableLocationSelection = true
+	t.Cleanup(func() { config.Config.EnableLocationSelection = false })
+	// known load zones, so that describing doesn't fetch them
+	cloudAction := &k6LoadTestCloudAction{loadZones: map[string][]k6cloud.LoadZone{config.DefaultCloudCredential: nil}}
+
+	for _, action := range []action_kit_sdk.Action[K6LoadTestRunState]{NewK6LoadTestRunAction(), NewK6LoadTestFixedDurationAction(), NewK6LoadTestCloudOutputAction(), cloudAction} {
+		description := action.Describe()
+		orders := make(map[int]string)
+		for _, p := range description.Parameters {
+			if p.Order == nil {
+				continue
+			}
+			other, clash := orders[*p.Order]
+			assert.False(t, clash, "%s: %s and %s share the order %d", description.Id, other, p.Name, *p.Order)
+			orders[*p.Order] = p.Name
+		}
+	}
+}
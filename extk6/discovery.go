@@ -10,20 +10,33 @@ import (
 	"github.com/steadybit/discovery-kit/go/discovery_kit_sdk"
 	"github.com/steadybit/extension-k6/config"
 	"github.com/steadybit/extension-kit/extbuild"
+	"maps"
+	"math"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 )
 
-type k6LocationDiscovery struct{}
+type k6LocationDiscovery struct {
+	// staticAttributes don't change during runtime and are determined once.
+	staticAttributes     map[string][]string
+	staticAttributesOnce sync.Once
+	cpu                  cpuSampler
+}
 
 var (
-	_ discovery_kit_sdk.TargetDescriber = (*k6LocationDiscovery)(nil)
+	_ discovery_kit_sdk.TargetDescriber    = (*k6LocationDiscovery)(nil)
+	_ discovery_kit_sdk.AttributeDescriber = (*k6LocationDiscovery)(nil)
 )
 
 func NewDiscovery() discovery_kit_sdk.TargetDiscovery {
 	discovery := &k6LocationDiscovery{}
 	return discovery_kit_sdk.NewCachedTargetDiscovery(discovery,
 		discovery_kit_sdk.WithRefreshTargetsNow(),
-		//No interval, target is not changing during runtime
+		// the running tests, VUs and CPU usage are changing
+		discovery_kit_sdk.WithRefreshTargetsInterval(context.Background(), config.Config.LocationRefreshInterval),
+		discovery_kit_sdk.WithRefreshTargetsTrigger(context.Background(), runsChanged, time.Second),
 	)
 }
 
@@ -31,7 +44,7 @@ func (e *k6LocationDiscovery) Describe() discovery_kit_api.DiscoveryDescription
 	return discovery_kit_api.DiscoveryDescription{
 		Id: targetType,
 		Discover: discovery_kit_api.DescribingEndpointReferenceWithCallInterval{
-			CallInterval: new(fmt.Sprintf("%ds", 300)),
+			CallInterval: new(fmt.Sprintf("%ds", max(int(config.Config.LocationRefreshInterval.Seconds()), 1))),
 		},
 	}
 }
@@ -49,7 +62,14 @@ func (e *k6LocationDiscovery) DescribeTarget() discovery_kit_api.TargetDescripti
 				{Attribute: "k8s.cluster-name"},
 				{Attribute: "k8s.namespace"},
 				{Attribute: "aws.account", FallbackAttributes: &[]string{"gcp.project.id", "azure.subscription.id"}},
-				{Attribute: "aws.zone", FallbackAttributes: &[]string{"gcp.zone", "azure.zone"}},
+				{Attribute: "aws.zone", FallbackAttributes: &[]string{"gcp.zone", "azure.zone", "k6.location.zone", "k6.location.region"}},
+				{Attribute: "k6.version"},
+				{Attribute: "k6.location.cpus"},
+				{Attribute: "k6.location.memory-limit"},
+				{Attribute: "k6.extension"},
+				{Attribute: "k6.running-tests"},
+				{Attribute: "k6.vus"},
+				{Attribute: "k6.location.cpu-usage"},
 			},
 			OrderBy: []discovery_kit_api.OrderBy{
 				{
@@ -61,8 +81,31 @@ func (e *k6LocationDiscovery) DescribeTarget() discovery_kit_api.TargetDescripti
 	}
 }
 
+func (e *k6LocationDiscovery) DescribeAttributes() []discovery_kit_api.AttributeDescription {
+	return []discovery_kit_api.AttributeDescription{
+		{Attribute: "k6.version", Label: discovery_kit_api.PluralLabel{One: "K6 version", Other: "K6 versions"}},
+		{Attribute: "k6.extension", Label: discovery_kit_api.PluralLabel{One: "K6 extension", Other: "K6 extensions"}},
+		{Attribute: "k6.location.cpus", Label: discovery_kit_api.PluralLabel{One: "CPUs", Other: "CPUs"}},
+		{Attribute: "k6.location.memory-limit", Label: discovery_kit_api.PluralLabel{One: "Memory limit", Other: "Memory limits"}},
+		{Attribute: "k6.location.region", Label: discovery_kit_api.PluralLabel{One: "Region", Other: "Regions"}},
+		{Attribute: "k6.location.zone", Label: discovery_kit_api.PluralLabel{One: "Zone", Other: "Zones"}},
+		{Attribute: "k6.running-tests", Label: discovery_kit_api.PluralLabel{One: "Running tests", Other: "Running tests"}},
+		{Attribute: "k6.vus", Label: discovery_kit_api.PluralLabel{One: "VUs", Other: "VUs"}},
+		{Attribute: "k6.location.cpu-usage", Label: discovery_kit_api.PluralLabel{One: "CPU usage", Other: "CPU usage"}},
+	}
+}
+
 func (e *k6LocationDiscovery) DiscoverTargets(_ context.Context) ([]discovery_kit_api.Target, error) {
-	attributes := make(map[string][]string)
+	e.staticAttributesOnce.Do(func() {
+		e.staticAttributes = locationAttributes()
+	})
+	attributes := maps.Clone(e.staticAttributes)
+	tests, vus := runningTestsLoad()
+	attributes["k6.running-tests"] = []string{strconv.Itoa(tests)}
+	attributes["k6.vus"] = []string{strconv.Itoa(vus)}
+	if usage, ok := e.cpu.sample(cgroupRoot, time.Now()); ok {
+		attributes["k6.location.cpu-usage"] = []string{strconv.Itoa(int(math.Round(usage)))}
+	}
 
 	var id, label string
 	if (config.Config.KubernetesNamespace != "") && (config.Config.KubernetesPodName != "") && (config.Config.KubernetesNodeName != "") {
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package extk6
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverTargetsReportsLiveAttributes(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cpu.stat"), []byte("usage_usec 1000000\nuser_usec 800000\n"), 0644))
+	previousRoot, previousCommand := cgroupRoot, k6VersionCommand
+	cgroupRoot = root
+	k6VersionCommand = []string{"printf", "%s", k6VersionOutput}
+	t.Cleanup(func() { cgroupRoot, k6VersionCommand = previousRoot, previousCommand })
+	discovery := &k6LocationDiscovery{}
+
+	registerRunningTest("discovery-test", "")
+	first, err := discovery.DiscoverTargets(context.TODO())
+	unregisterRunningTest("discovery-test")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cpu.stat"), []byte("usage_usec 2000000\nuser_usec 1600000\n"), 0644))
+	second, err := discovery.DiscoverTargets(context.TODO())
+	require.NoError(t, err)
+
+	require.Len(t, first, 1)
+	assert.Equal(t, []string{"v1.0.0"}, first[0].Attributes["k6.version"])
+	assert.NotEqual(t, first[0].Attributes["k6.running-tests"], second[0].Attributes["k6.running-tests"])
+	assert.NotContains(t, first[0].Attributes, "k6.location.cpu-usage", "the usage is known from the second sample on")
+	assert.Contains(t, second[0].Attributes, "k6.location.cpu-usage")
+	assert.Equal(t, first[0].Attributes["k6.version"], second[0].Attributes["k6.version"])
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// exit codes of k6, see https://github.com/grafana/k6/blob/master/errext/exitcodes/codes.go
+const (
+	exitCodeCloudTestRunFailed       = 97
+	exitCodeCloudFailedToGetProgress = 98
+	exitCodeThresholdsHaveFailed     = 99
+	exitCodeSetupTimeout             = 100
+	exitCodeTeardownTimeout          = 101
+	exitCodeGenericTimeout           = 102
+	exitCodeGenericEngine            = 103
+	exitCodeInvalidConfig            = 104
+	exitCodeExternalAbort            = 105
+	exitCodeCannotStartRESTAPI       = 106
+	exitCodeScriptException          = 107
+	exitCodeScriptAborted            = 108
+	exitCodeGoPanic                  = 109
+	exitCodeMarkedAsFailed           = 110
+)
+
+// abortOnFailLog is logged by k6 when a threshold with abortOnFail stopped the test.
+const abortOnFailLog = "abortOnFail enabled, stopping test prematurely"
+
+type exitCodeMapping struct {
+	status action_kit_api.ActionKitErrorStatus
+	title  string
+	hint   string
+}
+
+// exitCodes maps k6's exit codes to the error reported. Failed marks the system
+// under test as misbehaving, Errored marks the test itself as broken.
+var exitCodes = map[int]exitCodeMapping{
+	exitCodeCloudTestRunFailed: {
+		status: action_kit_api.Failed,
+		title:  "The k6 cloud test run has failed.",
+		hint:   "Check the test run in Grafana Cloud k6 for failed thresholds and errors.",
+	},
+	exitCodeCloudFailedToGetProgress: {
+		status: action_kit_api.Errored,
+		title:  "Failed to get the progress of the k6 cloud test run.",
+		hint:   "Check the connectivity to Grafana Cloud k6 and the state of the test run there.",
+	},
+	exitCodeThresholdsHaveFailed: {
+		status: action_kit_api.Failed,
+		title:  "Some thresholds have failed.",
+	},
+	exitCodeSetupTimeout: {
+		status: action_kit_api.Errored,
+		title:  "The setup() function of the k6 script timed out.",
+		hint:   "Increase the setupTimeout option of the script or make setup() faster.",
+	},
+	exitCodeTeardownTimeout: {
+		status: action_kit_api.Errored,
+		title:  "The teardown() function of the k6 script timed out.",
+		hint:   "Increase the teardownTimeout option of the script or make teardown() faster.",
+	},
+	exitCodeGenericTimeout: {
+		status: action_kit_api.Errored,
+		title:  "K6 timed out.",
+		hint:   "Check the k6 log for the operation that timed out.",
+	},
+	exitCodeGenericEngine: {
+		status: action_kit_api.Errored,
+		title:  "The k6 engine failed.",
+		hint:   "Check the k6 log for the cause, e.g. an output which could not be initialized.",
+	},
+	exitCodeInvalidConfig: {
+		status: action_kit_api.Errored,
+		title:  "The k6 configuration is invalid.",
+		hint:   "Check the options of the script and the parameters of the action.",
+	},
+	exitCodeExternalAbort: {
+		status: action_kit_api.Errored,
+		title:  "K6 was aborted externally.",
+		hint:   "K6 received a signal to stop before the test was completed.",
+	},
+	exitCodeCannotStartRESTAPI: {
+		status: action_kit_api.Errored,
+		title:  "K6 could not start its REST API.",
+		hint:   "The port of the REST API might already be in use, retry the experiment.",
+	},
+	exitCodeScriptException: {
+		status: action_kit_api.Errored,
+		title:  "The k6 script threw an exception.",
+		hint:   "Check the k6 log for the exception and its stack trace.",
+	},
+	exitCodeScriptAborted: {
+		status: action_kit_api.Failed,
+		title:  "The k6 script aborted the test.",
+		hint:   "The script called test.abort().",
+	},
+	exitCodeGoPanic: {
+		status: action_kit_api.Errored,
+		title:  "K6 crashed.",
+		hint:   "Check the k6 log for the panic and consider reporting it to the k6 maintainers.",
+	},
+	exitCodeMarkedAsFailed: {
+		status: action_kit_api.Failed,
+		title:  "The k6 script marked the test as failed.",
+		hint:   "The script called test.fail().",
+	},
+}
+
+var abortOnFailMapping = exitCodeMapping{
+	status: action_kit_api.Failed,
+	title:  "Thresholds with abortOnFail have failed, the test was stopped early.",
+}
+
+func isThresholdExitCode(exitCode int) bool {
+	return exitCode == exitCodeCloudTestRunFailed || exitCode == exitCodeThresholdsHaveFailed
+}
+
+// exitCodeError describes why k6 exited with a non-zero exit code. The detail is
+// made of the failed thresholds, or the last error logged, followed by the hint.
+func exitCodeError(exitCode int, abortedOnFail bool, stdOut []string, failedThresholds []thresholdFailure) *action_kit_api.ActionKitError {
+	mapping, known := exitCodes[exitCode]
+	if !known {
+		mapping = exitCodeMapping{
+			status: action_kit_api.Errored,
+			title:  fmt.Sprintf("K6 run failed, exit-code %d", exitCode),
+		}
+	}
+	if exitCode == exitCodeThresholdsHaveFailed && abortedOnFail {
+		mapping = abortOnFailMapping
+	}
+
+	details := make([]string, 0)
+	if len(failedThresholds) > 0 {
+		details = append(details, *thresholdFailureDetail(failedThresholds))
+	} else if stdOutError := extractErrorFromStdOut(stdOut); stdOutError != nil {
+		details = append(details, *stdOutError)
+	}
+	if mapping.hint != "" {
+		details = append(details, mapping.hint)
+	}
+
+	result := &action_kit_api.ActionKitError{
+		Status: extutil.Ptr(mapping.status),
+		Title:  mapping.title,
+	}
+	if len(details) > 0 {
+		result.Detail = extutil.Ptr(strings.Join(details, "\n"))
+	}
+	return result
+}
+
+func containsLine(lines []string, s string) bool {
+	for _, line := range lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statusAfterExit(t *testing.T, script string) *action_kit_api.StatusResult {
+	t.Helper()
+	state := startFakeK6(t, script, 0)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	result, err := status(state)
+	require.NoError(t, err)
+	require.True(t, result.Completed)
+	return result
+}
+
+func Test_status_maps_exit_codes(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		status   action_kit_api.ActionKitErrorStatus
+		title    string
+	}{
+		{exitCode: 97, status: action_kit_api.Failed, title: "The k6 cloud test run has failed."},
+		{exitCode: 98, status: action_kit_api.Errored, title: "Failed to get the progress of the k6 cloud test run."},
+		{exitCode: 99, status: action_kit_api.Failed, title: "Some thresholds have failed."},
+		{exitCode: 100, status: action_kit_api.Errored, title: "The setup() function of the k6 script timed out."},
+		{exitCode: 101, status: action_kit_api.Errored, title: "The teardown() function of the k6 script timed out."},
+		{exitCode: 102, status: action_kit_api.Errored, title: "K6 timed out."},
+		{exitCode: 103, status: action_kit_api.Errored, title: "The k6 engine failed."},
+		{exitCode: 104, status: action_kit_api.Errored, title: "The k6 configuration is invalid."},
+		{exitCode: 105, status: action_kit_api.Errored, title: "K6 was aborted externally."},
+		{exitCode: 106, status: action_kit_api.Errored, title: "K6 could not start its REST API."},
+		{exitCode: 107, status: action_kit_api.Errored, title: "The k6 script threw an exception."},
+		{exitCode: 108, status: action_kit_api.Failed, title: "The k6 script aborted the test."},
+		{exitCode: 109, status: action_kit_api.Errored, title: "K6 crashed."},
+		{exitCode: 110, status: action_kit_api.Failed, title: "The k6 script marked the test as failed."},
+		{exitCode: 1, status: action_kit_api.Errored, title: "K6 run failed, exit-code 1"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("exit code %d", tt.exitCode), func(t *testing.T) {
+			result := statusAfterExit(t, fmt.Sprintf("exit %d", tt.exitCode))
+
+			require.NotNil(t, result.Error)
+			assert.Equal(t, tt.status, *result.Error.Status)
+			assert.Equal(t, tt.title, result.Error.Title)
+		})
+	}
+}
+
+func Test_status_reports_logged_error_and_hint(t *testing.T) {
+	result := statusAfterExit(t, `printf '%s\n' 'time="2026-03-04T10:11:12Z" level=error msg="ReferenceError: foo is not defined\n\tat default (file:///test.js:5:2(3))"'; exit 107`)
+
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "ReferenceError: foo is not defined\n\tat default (file:///test.js:5:2(3))\nCheck the k6 log for the exception and its stack trace.", *result.Error.Detail)
+}
+
+func Test_status_reports_success(t *testing.T) {
+	result := statusAfterExit(t, "exit 0")
+
+	assert.Nil(t, result.Error)
+}
+
+func Test_status_detects_abort_on_fail(t *testing.T) {
+	state := startFakeK6(t, `echo 'level=error msg="thresholds on metrics '"'"'http_req_failed'"'"' were crossed; at least one has abortOnFail enabled, stopping test prematurely"'; sleep 0.5; exit 99`, 0)
+
+	// k6 logs the reason while still shutting down
+	result, err := status(state)
+	require.NoError(t, err)
+	assert.False(t, result.Completed)
+	assert.True(t, state.AbortedOnFail)
+
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	result, err = status(state)
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, action_kit_api.Failed, *result.Error.Status)
+	assert.Equal(t, "Thresholds with abortOnFail have failed, the test was stopped early.", result.Error.Title)
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"strings"
+	"sync"
+)
+
+// isolations holds the isolation of each k6 started by this extension instance
+// by its command state id, until it is released by stop.
+var isolations = sync.Map{}
+
+// releaseIsolation cleans up the isolation of the k6 process, which must be done
+// after the processes it left behind have been killed, as these would keep its
+// cgroup busy.
+func releaseIsolation(cmdStateId string) {
+	if value, ok := isolations.LoadAndDelete(cmdStateId); ok {
+		value.(*isolation).cleanup()
+	}
+}
+
+// scrubbedEnv returns the variables of environ that are allowed to be passed to
+// k6, so that the extension's own configuration and secrets don't leak into the
+// load test scripts. Entries of allowList ending with * match any suffix.
+func scrubbedEnv(environ []string, allowList []string) []string {
+	result := make([]string, 0, len(environ))
+	for _, variable := range environ {
+		key, _, _ := strings.Cut(variable, "=")
+		if envAllowed(key, allowList) {
+			result = append(result, variable)
+		}
+	}
+	return result
+}
+
+func envAllowed(key string, allowList []string) bool {
+	for _, allowed := range allowList {
+		allowed = strings.TrimSpace(allowed)
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == allowed {
+			return true
+		}
+	}
+	return false
+}
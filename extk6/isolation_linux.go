@@ -0,0 +1,223 @@
+//go:build linux
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-k6/config"
+	"golang.org/x/sys/unix"
+)
+
+// cpuPeriod is the cgroup v2 CPU period in microseconds the quota refers to.
+const cpuPeriod = 100000
+
+// fallbackNice is the priority k6 runs with if its CPU cannot be limited by a cgroup.
+const fallbackNice = 10
+
+// isolation confines a k6 process to its own process group and to the
+// configured resource limits.
+type isolation struct {
+	// cgroupDir is the cgroup v2 sub-group the process runs in, empty if the
+	// cgroup file system is not writable.
+	cgroupDir string
+	cgroupFd  *os.File
+}
+
+// isolate prepares cmd to be started in its own process group and, if resource
+// limits are configured and possible, in a cgroup v2 sub-group named by id.
+func isolate(cmd *exec.Cmd, id string) *isolation {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	result := &isolation{}
+	if config.Config.RunCpuLimit <= 0 && config.Config.RunMemoryLimit <= 0 {
+		return result
+	}
+
+	parent, err := runsCgroup.dir()
+	if err != nil {
+		log.Debug().Err(err).Msg("Cannot create cgroups for k6, falling back to a lower priority.")
+		return result
+	}
+	dir, err := createCgroup(parent, "k6-"+id)
+	if err != nil {
+		log.Debug().Err(err).Msg("Cannot create a cgroup for k6, falling back to a lower priority.")
+		return result
+	}
+	fd, err := os.Open(dir)
+	if err != nil {
+		log.Debug().Err(err).Msg("Cannot open the cgroup for k6, falling back to a lower priority.")
+		_ = os.Remove(dir)
+		return result
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(fd.Fd())
+	result.cgroupDir = dir
+	result.cgroupFd = fd
+	return result
+}
+
+// extensionCgroup is the leaf cgroup the extension moves itself into, so that
+// controllers can be enabled for the cgroups of k6 beside it, as cgroup v2 only
+// allows them in cgroups without processes of their own.
+const extensionCgroup = "extension"
+
+// cgroupParent is the cgroup the cgroups of k6 are created in, set up once.
+type cgroupParent struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+var runsCgroup = &cgroupParent{}
+
+func (p *cgroupParent) dir() (string, error) {
+	p.once.Do(func() {
+		p.path, p.err = setupCgroupParent(cgroupRoot, procRoot)
+	})
+	return p.path, p.err
+}
+
+// setupCgroupParent moves the extension from its own cgroup into a leaf child
+// and enables the CPU and memory controllers for the children of its cgroup,
+// which it returns.
+func setupCgroupParent(root string, proc string) (string, error) {
+	own, err := ownCgroup(proc)
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Join(root, own)
+	if filepath.Base(own) == extensionCgroup {
+		// moved already, e.g. by a previous extension process in the same cgroup
+		parent = filepath.Dir(parent)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.subtree_control")); err != nil {
+		return "", fmt.Errorf("%s is not a cgroup v2 file system", root)
+	}
+
+	leaf := filepath.Join(parent, extensionCgroup)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		return "", fmt.Errorf("failed to move the extension into %s: %w", leaf, err)
+	}
+
+	required := []string{"cpu", "memory"}
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+cpu +memory"), 0); err != nil {
+		return "", fmt.Errorf("failed to enable the controllers of %s: %w", parent, err)
+	}
+	content, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return "", err
+	}
+	enabled := strings.Fields(strings.ReplaceAll(string(content), "+", ""))
+	for _, controller := range required {
+		if !slices.Contains(enabled, controller) {
+			return "", fmt.Errorf("the %s controller is not available in %s", controller, parent)
+		}
+	}
+	return parent, nil
+}
+
+// ownCgroup reads the cgroup v2 path of the extension process.
+func ownCgroup(proc string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(proc, "self", "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("the extension is not in a cgroup v2 hierarchy")
+}
+
+// createCgroup creates the cgroup v2 sub-group name below parent with the
+// configured CPU and memory limits.
+func createCgroup(parent string, name string) (string, error) {
+	dir := filepath.Join(parent, name)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.procs")); err != nil {
+		_ = os.Remove(dir)
+		return "", fmt.Errorf("%s is not a cgroup v2 file system", parent)
+	}
+	if limit := config.Config.RunCpuLimit; limit > 0 {
+		quota := fmt.Sprintf("%d %d", int64(limit*cpuPeriod), cpuPeriod)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(quota), 0); err != nil {
+			_ = os.Remove(dir)
+			return "", err
+		}
+	}
+	if limit := config.Config.RunMemoryLimit; limit > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(fmt.Sprint(int64(limit))), 0); err != nil {
+			_ = os.Remove(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// started lowers the priority of the started process if its CPU could not be
+// limited by a cgroup, so that k6 yields the CPU to the extension. There is no
+// fallback for the memory limit, as limiting the address space makes the Go
+// runtime of k6 fail long before the memory is used.
+func (i *isolation) started(pid int) {
+	if i.cgroupFd != nil {
+		_ = i.cgroupFd.Close()
+		i.cgroupFd = nil
+	}
+	if i.cgroupDir == "" && config.Config.RunCpuLimit > 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, pid, fallbackNice); err != nil {
+			log.Warn().Err(err).Msg("Failed to lower the priority of k6.")
+		}
+	}
+}
+
+// memoryUnlimited reports whether the configured memory limit could not be
+// enforced, as k6 does not run in a cgroup.
+func (i *isolation) memoryUnlimited() bool {
+	return i.cgroupDir == "" && config.Config.RunMemoryLimit > 0
+}
+
+// cleanup removes the cgroup sub-group once the process has exited, killing the
+// processes still left in it.
+func (i *isolation) cleanup() {
+	if i.cgroupFd != nil {
+		_ = i.cgroupFd.Close()
+		i.cgroupFd = nil
+	}
+	if i.cgroupDir == "" {
+		return
+	}
+	err := os.Remove(i.cgroupDir)
+	if errors.Is(err, syscall.EBUSY) {
+		log.Warn().Msgf("Killing the processes left in cgroup %s.", i.cgroupDir)
+		if err = os.WriteFile(filepath.Join(i.cgroupDir, "cgroup.kill"), []byte("1"), 0); err == nil {
+			deadline := time.Now().Add(killTimeout)
+			for err = os.Remove(i.cgroupDir); errors.Is(err, syscall.EBUSY) && time.Now().Before(deadline); err = os.Remove(i.cgroupDir) {
+				time.Sleep(groupPollInterval)
+			}
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to remove cgroup %s.", i.cgroupDir)
+	}
+}
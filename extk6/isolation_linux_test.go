@@ -0,0 +1,129 @@
+//go:build linux
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withResourceLimits(t *testing.T, cpu float64, memory config.ByteSize) {
+	previous, previousCgroupRoot, previousRunsCgroup := config.Config, cgroupRoot, runsCgroup
+	config.Config.RunCpuLimit = cpu
+	config.Config.RunMemoryLimit = memory
+	cgroupRoot = t.TempDir()
+	runsCgroup = &cgroupParent{}
+	t.Cleanup(func() { config.Config, cgroupRoot, runsCgroup = previous, previousCgroupRoot, previousRunsCgroup })
+}
+
+func Test_isolate_starts_process_group(t *testing.T) {
+	withResourceLimits(t, 0, 0)
+	cmd := exec.Command("sleep", "1")
+
+	isolated := isolate(cmd, "test")
+	require.NoError(t, cmd.Start())
+	isolated.started(cmd.Process.Pid)
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		isolated.cleanup()
+	}()
+
+	pgid, err := syscall.Getpgid(cmd.Process.Pid)
+	require.NoError(t, err)
+	assert.Equal(t, cmd.Process.Pid, pgid)
+}
+
+func Test_isolate_falls_back_to_a_lower_priority(t *testing.T) {
+	withResourceLimits(t, 1, 512*1024*1024)
+	cmd := exec.Command("sh", "-c", "sleep 0.5; ulimit -v; nice")
+
+	isolated := isolate(cmd, "test")
+	assert.Empty(t, isolated.cgroupDir)
+	assert.NoDirExists(t, filepath.Join(cgroupRoot, "k6-test"))
+	assert.True(t, isolated.memoryUnlimited())
+	var output strings.Builder
+	cmd.Stdout = &output
+	require.NoError(t, cmd.Start())
+	isolated.started(cmd.Process.Pid)
+	require.NoError(t, cmd.Wait())
+	isolated.cleanup()
+
+	assert.Equal(t, []string{"unlimited", "10"}, strings.Fields(output.String()))
+}
+
+func Test_start_reports_unenforced_memory_limit(t *testing.T) {
+	withResourceLimits(t, 0, 512*1024*1024)
+	state := &K6LoadTestRunState{Command: []string{"true"}, ExecutionId: uuid.New()}
+
+	result, err := start(state, nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, "The memory limit of the load test is not enforced, as the extension cannot create cgroups.", (*result.Messages)[0].Message)
+	assert.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+}
+
+func Test_statusQueued_reports_unenforced_memory_limit(t *testing.T) {
+	withLimits(t, 1, 0, time.Minute)
+	withResourceLimits(t, 0, 512*1024*1024)
+	admissions.admit("running", 1, time.Now(), false)
+	state := queuedState(t, "exit 0")
+	_, err := startAdmitted(state, nil)
+	require.NoError(t, err)
+	admissions.release("running")
+
+	status, err := statusQueued(state, nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, status.Messages)
+	require.Len(t, *status.Messages, 2)
+	assert.Contains(t, (*status.Messages)[0].Message, "The load test was started after waiting")
+	assert.Equal(t, "The memory limit of the load test is not enforced, as the extension cannot create cgroups.", (*status.Messages)[1].Message)
+	assert.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+}
+
+func Test_setupCgroupParent_moves_the_extension_into_a_leaf(t *testing.T) {
+	root, proc := t.TempDir(), t.TempDir()
+	service := filepath.Join(root, "system.slice", "steadybit-extension-k6.service")
+	require.NoError(t, os.MkdirAll(service, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(service, "cgroup.subtree_control"), nil, 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(proc, "self"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(proc, "self", "cgroup"), []byte("0::/system.slice/steadybit-extension-k6.service\n"), 0o644))
+
+	parent, err := setupCgroupParent(root, proc)
+
+	require.NoError(t, err)
+	assert.Equal(t, service, parent)
+	procs, err := os.ReadFile(filepath.Join(service, "extension", "cgroup.procs"))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), string(procs))
+	controllers, err := os.ReadFile(filepath.Join(service, "cgroup.subtree_control"))
+	require.NoError(t, err)
+	assert.Equal(t, "+cpu +memory", string(controllers))
+}
+
+func Test_setupCgroupParent_requires_cgroup_v2(t *testing.T) {
+	proc := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(proc, "self"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(proc, "self", "cgroup"), []byte("12:memory:/docker/abc\n"), 0o644))
+
+	_, err := setupCgroupParent(t.TempDir(), proc)
+
+	assert.EqualError(t, err, "the extension is not in a cgroup v2 hierarchy")
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"os/exec"
+
+	"github.com/steadybit/extension-k6/config"
+)
+
+// isolation is a no-op on other operating systems than Linux, which the
+// extension is only built for in development.
+type isolation struct{}
+
+func isolate(_ *exec.Cmd, _ string) *isolation {
+	return &isolation{}
+}
+
+func (i *isolation) started(_ int) {}
+
+func (i *isolation) memoryUnlimited() bool {
+	return config.Config.RunMemoryLimit > 0
+}
+
+func (i *isolation) cleanup() {}
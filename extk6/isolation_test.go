@@ -0,0 +1,46 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_scrubbedEnv(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"HOME=/root",
+		"STEADYBIT_EXTENSION_CLOUD_API_TOKEN=secret",
+		"K6_NO_USAGE_REPORT=true",
+		"LC_ALL=C",
+		"AWS_SECRET_ACCESS_KEY=secret",
+		"PATHOLOGICAL=1",
+	}
+
+	env := scrubbedEnv(environ, []string{"PATH", "HOME", " LC_*", "K6_*"})
+
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/root", "K6_NO_USAGE_REPORT=true", "LC_ALL=C"}, env)
+}
+
+func Test_scrubbedEnv_without_allow_list(t *testing.T) {
+	assert.Empty(t, scrubbedEnv([]string{"PATH=/usr/bin"}, nil))
+}
+
+func Test_stop_releases_isolation_after_k6_has_exited(t *testing.T) {
+	state := startFakeK6(t, `exit 0`, time.Second)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	_, ok := isolations.Load(state.CmdStateID)
+	require.True(t, ok, "isolation must be kept until stopped")
+
+	_, err := stop(state)
+
+	require.NoError(t, err)
+	_, ok = isolations.Load(state.CmdStateID)
+	assert.False(t, ok)
+}
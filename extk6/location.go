@@ -0,0 +1,181 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// k6VersionCommand prints the version and the extensions of the k6 binary.
+	k6VersionCommand = []string{"k6", "version"}
+	// cgroupRoot is where the cgroup file system of the extension's container is mounted.
+	cgroupRoot = "/sys/fs/cgroup"
+
+	k6VersionPattern = regexp.MustCompile(`k6 (v\S+)`)
+)
+
+// unlimitedMemory is reported by cgroup v1 if there is no memory limit.
+const unlimitedMemory = int64(1) << 62
+
+// locationAttributes describe the capacity and the capabilities of the k6 location.
+func locationAttributes() map[string][]string {
+	attributes := map[string][]string{
+		"k6.location.cpus": {strconv.Itoa(runtime.NumCPU())},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, k6VersionCommand[0], k6VersionCommand[1:]...).Output(); err != nil {
+		log.Warn().Err(err).Msg("Failed to get the k6 version.")
+	} else {
+		version, extensions := parseK6Version(string(output))
+		if version != "" {
+			attributes["k6.version"] = []string{version}
+		}
+		if len(extensions) > 0 {
+			attributes["k6.extension"] = extensions
+		}
+	}
+
+	if limit, ok := memoryLimit(cgroupRoot); ok {
+		attributes["k6.location.memory-limit"] = []string{formatBytes(limit)}
+	}
+	if region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION", "GOOGLE_CLOUD_REGION", "AZURE_REGION"); region != "" {
+		attributes["k6.location.region"] = []string{region}
+	}
+	if zone := firstEnv("AWS_AVAILABILITY_ZONE", "GOOGLE_CLOUD_ZONE", "AZURE_ZONE"); zone != "" {
+		attributes["k6.location.zone"] = []string{zone}
+	}
+	return attributes
+}
+
+// parseK6Version parses the output of `k6 version`, returning the version and
+// the names of the compiled-in extensions, e.g. xk6-kafka.
+func parseK6Version(output string) (string, []string) {
+	version := ""
+	if match := k6VersionPattern.FindStringSubmatch(output); match != nil {
+		version = match[1]
+	}
+
+	var extensions []string
+	inExtensions := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "Extensions:" {
+			inExtensions = true
+			continue
+		}
+		fields := strings.Fields(line)
+		if !inExtensions || len(fields) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			inExtensions = false
+			continue
+		}
+		name := path.Base(fields[0])
+		if len(extensions) == 0 || extensions[len(extensions)-1] != name {
+			extensions = append(extensions, name)
+		}
+	}
+	return version, extensions
+}
+
+// memoryLimit reads the memory limit of the cgroup, supporting cgroup v2 and v1.
+func memoryLimit(root string) (int64, bool) {
+	for _, file := range []string{"memory.max", filepath.Join("memory", "memory.limit_in_bytes")} {
+		content, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(content))
+		if value == "max" {
+			return 0, false
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 || limit >= unlimitedMemory {
+			return 0, false
+		}
+		return limit, true
+	}
+	return 0, false
+}
+
+func formatBytes(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(bytes)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.1f", value), "0"), ".") + " " + units[unit]
+}
+
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// cpuSampler computes the CPU usage of the location between two samples.
+type cpuSampler struct {
+	mutex      sync.Mutex
+	lastUsage  time.Duration
+	lastSample time.Time
+}
+
+// sample returns the CPU usage in percent of all CPUs since the last sample, if
+// there is one.
+func (s *cpuSampler) sample(root string, now time.Time) (float64, bool) {
+	usage, ok := cpuUsageTime(root)
+	if !ok {
+		return 0, false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	lastUsage, lastSample := s.lastUsage, s.lastSample
+	s.lastUsage, s.lastSample = usage, now
+	if lastSample.IsZero() || !now.After(lastSample) || usage < lastUsage {
+		return 0, false
+	}
+	return float64(usage-lastUsage) / float64(now.Sub(lastSample)) / float64(runtime.NumCPU()) * 100, true
+}
+
+// cpuUsageTime reads the CPU time consumed by the cgroup, supporting cgroup v2 and v1.
+func cpuUsageTime(root string) (time.Duration, bool) {
+	if content, err := os.ReadFile(filepath.Join(root, "cpu.stat")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "usage_usec" {
+				usec, err := strconv.ParseInt(fields[1], 10, 64)
+				return time.Duration(usec) * time.Microsecond, err == nil
+			}
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(root, "cpuacct", "cpuacct.usage")); err == nil {
+		ns, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+		return time.Duration(ns), err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const k6VersionOutput = `k6 v1.0.0 (commit/41b4984b75, go1.24.2, linux/amd64)
+Extensions:
+  github.com/grafana/xk6-dns v0.1.0, k6/x/dns [js]
+  github.com/mostafa/xk6-kafka v1.0.0, k6/x/kafka [js]
+  github.com/grafana/xk6-output-influxdb v0.5.0, xk6-influxdb [output]
+  github.com/grafana/xk6-output-influxdb v0.5.0, k6/x/influxdb [js]
+`
+
+func Test_parseK6Version(t *testing.T) {
+	version, extensions := parseK6Version(k6VersionOutput)
+
+	assert.Equal(t, "v1.0.0", version)
+	assert.Equal(t, []string{"xk6-dns", "xk6-kafka", "xk6-output-influxdb"}, extensions)
+}
+
+func Test_parseK6Version_without_extensions(t *testing.T) {
+	version, extensions := parseK6Version("k6 v0.57.0 (go1.23.4, linux/arm64)\n")
+
+	assert.Equal(t, "v0.57.0", version)
+	assert.Empty(t, extensions)
+}
+
+func Test_memoryLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		wantLimit int64
+		wantOk    bool
+	}{
+		{name: "cgroup v2", file: "memory.max", content: "2147483648\n", wantLimit: 2147483648, wantOk: true},
+		{name: "cgroup v2 unlimited", file: "memory.max", content: "max\n", wantOk: false},
+		{name: "cgroup v1", file: "memory/memory.limit_in_bytes", content: "536870912\n", wantLimit: 536870912, wantOk: true},
+		{name: "cgroup v1 unlimited", file: "memory/memory.limit_in_bytes", content: "9223372036854771712\n", wantOk: false},
+		{name: "no cgroup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if tt.file != "" {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, tt.file)), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(root, tt.file), []byte(tt.content), 0644))
+			}
+
+			limit, ok := memoryLimit(root)
+
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantLimit, limit)
+		})
+	}
+}
+
+func Test_formatBytes(t *testing.T) {
+	assert.Equal(t, "512 MiB", formatBytes(512*1024*1024))
+	assert.Equal(t, "1.5 GiB", formatBytes(1536*1024*1024))
+	assert.Equal(t, "100 B", formatBytes(100))
+}
+
+func Test_locationAttributes(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "memory.max"), []byte("4294967296"), 0644))
+	previousRoot, previousCommand := cgroupRoot, k6VersionCommand
+	cgroupRoot = root
+	k6VersionCommand = []string{"printf", "%s", k6VersionOutput}
+	t.Cleanup(func() { cgroupRoot, k6VersionCommand = previousRoot, previousCommand })
+	t.Setenv("AWS_REGION", "eu-central-1")
+
+	attributes := locationAttributes()
+
+	assert.Equal(t, []string{"v1.0.0"}, attributes["k6.version"])
+	assert.Equal(t, []string{"xk6-dns", "xk6-kafka", "xk6-output-influxdb"}, attributes["k6.extension"])
+	assert.Equal(t, []string{strconv.Itoa(runtime.NumCPU())}, attributes["k6.location.cpus"])
+	assert.Equal(t, []string{"4 GiB"}, attributes["k6.location.memory-limit"])
+	assert.Equal(t, []string{"eu-central-1"}, attributes["k6.location.region"])
+}
+
+func Test_cpuSampler(t *testing.T) {
+	root := t.TempDir()
+	writeUsage := func(usec string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "cpu.stat"), []byte("usage_usec "+usec+"\nuser_usec 0\nsystem_usec 0\n"), 0644))
+	}
+	sampler := cpuSampler{}
+	start := time.Now()
+
+	writeUsage("1000000")
+	_, ok := sampler.sample(root, start)
+	assert.False(t, ok, "the first sample has nothing to compare with")
+
+	// one CPU second per second
+	writeUsage("3000000")
+	usage, ok := sampler.sample(root, start.Add(2*time.Second))
+	assert.True(t, ok)
+	assert.InDelta(t, 100/float64(runtime.NumCPU()), usage, 0.001)
+}
+
+func Test_cpuUsageTime_cgroup_v1(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "cpuacct"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cpuacct", "cpuacct.usage"), []byte("1500000000\n"), 0644))
+
+	usage, ok := cpuUsageTime(root)
+
+	assert.True(t, ok)
+	assert.Equal(t, 1500*time.Millisecond, usage)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+)
+
+// logLine is a line of k6's console output. Log entries written by k6 or the
+// script's console are formatted as logfmt, or as JSON with `--log-format json`.
+// Any other output, like the banner and the end-of-test summary, is plain text.
+type logLine struct {
+	level     action_kit_api.MessageLevel
+	message   string
+	timestamp *time.Time
+	fields    map[string]string
+}
+
+func parseLogLine(line string) logLine {
+	if strings.HasPrefix(line, "{") {
+		if fields, ok := parseJsonLogLine(line); ok {
+			return toLogLine(fields)
+		}
+	}
+	if strings.Contains(line, "level=") {
+		if fields, ok := parseLogfmtLine(line); ok {
+			return toLogLine(fields)
+		}
+	}
+	return logLine{level: action_kit_api.Info, message: line}
+}
+
+func toLogLine(fields map[string]string) logLine {
+	result := logLine{
+		level:   toMessageLevel(fields["level"]),
+		message: fields["msg"],
+		fields:  make(map[string]string),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, fields["time"]); err == nil {
+		result.timestamp = &t
+	}
+	for key, value := range fields {
+		if key != "level" && key != "msg" && key != "time" {
+			result.fields[key] = value
+		}
+	}
+	return result
+}
+
+func toMessageLevel(level string) action_kit_api.MessageLevel {
+	switch strings.ToLower(level) {
+	case "trace", "debug":
+		return action_kit_api.Debug
+	case "warn", "warning":
+		return action_kit_api.Warn
+	case "error", "fatal", "panic":
+		return action_kit_api.Error
+	default:
+		return action_kit_api.Info
+	}
+}
+
+func parseJsonLogLine(line string) (map[string]string, bool) {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return nil, false
+	}
+	if _, ok := raw["level"]; !ok {
+		return nil, false
+	}
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if s, ok := value.(string); ok {
+			fields[key] = s
+		} else if encoded, err := json.Marshal(value); err == nil {
+			fields[key] = string(encoded)
+		}
+	}
+	return fields, true
+}
+
+// parseLogfmtLine parses `key=value` pairs separated by spaces, values may be
+// double-quoted with Go escaping, as written by k6's logrus text formatter.
+func parseLogfmtLine(line string) (map[string]string, bool) {
+	fields := make(map[string]string)
+	rest := strings.TrimSpace(line)
+	for len(rest) > 0 {
+		eq := strings.IndexAny(rest, "= ")
+		if eq <= 0 || rest[eq] != '=' {
+			return nil, false
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := closingQuote(rest)
+			if end < 0 {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			value = unquoted
+			rest = rest[end+1:]
+		} else if space := strings.IndexByte(rest, ' '); space >= 0 {
+			value = rest[:space]
+			rest = rest[space:]
+		} else {
+			value = rest
+			rest = ""
+		}
+		fields[key] = value
+		rest = strings.TrimLeft(rest, " ")
+	}
+	_, ok := fields["level"]
+	return fields, ok
+}
+
+// closingQuote returns the index of the quote terminating the quoted string s
+// starts with, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseLogLine(t *testing.T) {
+	timestamp := time.Date(2026, 3, 4, 10, 11, 12, 0, time.UTC)
+	tests := []struct {
+		name string
+		line string
+		want logLine
+	}{
+		{
+			name: "logfmt",
+			line: `time="2026-03-04T10:11:12Z" level=warning msg="Request Failed" error="Get \"http://test\": dial tcp: lookup test"`,
+			want: logLine{level: action_kit_api.Warn, message: "Request Failed", timestamp: &timestamp, fields: map[string]string{"error": `Get "http://test": dial tcp: lookup test`}},
+		},
+		{
+			name: "logfmt console",
+			line: `time="2026-03-04T10:11:12Z" level=info msg="hello world" source=console`,
+			want: logLine{level: action_kit_api.Info, message: "hello world", timestamp: &timestamp, fields: map[string]string{"source": "console"}},
+		},
+		{
+			name: "logfmt unquoted",
+			line: `level=error msg=something`,
+			want: logLine{level: action_kit_api.Error, message: "something", fields: map[string]string{}},
+		},
+		{
+			name: "json",
+			line: `{"level":"debug","msg":"tick","source":"console","time":"2026-03-04T10:11:12Z","vu":3}`,
+			want: logLine{level: action_kit_api.Debug, message: "tick", timestamp: &timestamp, fields: map[string]string{"source": "console", "vu": "3"}},
+		},
+		{
+			name: "fatal",
+			line: `level=fatal msg="could not initialize"`,
+			want: logLine{level: action_kit_api.Error, message: "could not initialize", fields: map[string]string{}},
+		},
+		{
+			name: "plain text",
+			line: `     ✓ status is 200`,
+			want: logLine{level: action_kit_api.Info, message: `     ✓ status is 200`},
+		},
+		{
+			name: "plain text mentioning level=",
+			line: `the option level=1 is not supported`,
+			want: logLine{level: action_kit_api.Info, message: `the option level=1 is not supported`},
+		},
+		{
+			name: "unterminated quote",
+			line: `level=info msg="broken`,
+			want: logLine{level: action_kit_api.Info, message: `level=info msg="broken`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseLogLine(tt.line))
+		})
+	}
+}
+
+func Test_stdOutToMessages(t *testing.T) {
+	messages := stdOutToMessages([]string{
+		"\n",
+		"  execution: local\n",
+		"time=\"2026-03-04T10:11:12Z\" level=error msg=\"Uncaught (in promise) boom\" source=console\n",
+	})
+
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "execution: local", messages[0].Message)
+	assert.Equal(t, action_kit_api.Info, *messages[0].Level)
+	assert.Nil(t, messages[0].Fields)
+	assert.Equal(t, "Uncaught (in promise) boom", messages[1].Message)
+	assert.Equal(t, action_kit_api.Error, *messages[1].Level)
+	assert.Equal(t, action_kit_api.MessageFields{"source": "console"}, *messages[1].Fields)
+	assert.NotNil(t, messages[1].Timestamp)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+)
+
+// liveMetrics lists the k6 metrics polled from the REST API and the sample stats
+// reported for each of them.
+var liveMetrics = []struct {
+	name  string
+	title string
+	unit  string
+	stats []string
+}{
+	{name: "http_req_duration", title: "HTTP Request Duration", unit: "ms", stats: []string{"avg", "med", "p(90)", "p(95)", "max"}},
+	{name: "http_reqs", title: "HTTP Requests", unit: "req/s", stats: []string{"rate"}},
+	{name: "http_req_failed", title: "HTTP Request Failure Rate", unit: "%", stats: []string{"rate"}},
+	{name: "vus", title: "Virtual Users", unit: "VUs", stats: []string{"value"}},
+}
+
+var metricsClient = &http.Client{Timeout: 2 * time.Second}
+
+type metricsResponse struct {
+	Data []struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Type   string             `json:"type"`
+			Sample map[string]float64 `json:"sample"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// freeApiAddress returns a loopback address with a currently unused port for the
+// k6 REST API of a single execution.
+func freeApiAddress() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = listener.Close() }()
+	return listener.Addr().String(), nil
+}
+
+func metricName(k6Metric string) string {
+	return fmt.Sprintf("k6_%s", k6Metric)
+}
+
+// fetchMetrics polls the k6 REST API at address and converts the live samples of
+// the metrics listed in liveMetrics. It returns nil if the API is not reachable,
+// e.g. because k6 has not yet started or has already finished.
+func fetchMetrics(address string) []action_kit_api.Metric {
+	if address == "" {
+		return nil
+	}
+
+	res, err := metricsClient.Get(fmt.Sprintf("http://%s/v1/metrics", address))
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to fetch k6 metrics from %s", address)
+		return nil
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode != http.StatusOK {
+		log.Debug().Msgf("Failed to fetch k6 metrics from %s: %s", address, res.Status)
+		return nil
+	}
+
+	var body metricsResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		log.Debug().Err(err).Msgf("Failed to decode k6 metrics from %s", address)
+		return nil
+	}
+
+	samples := make(map[string]map[string]float64, len(body.Data))
+	for _, data := range body.Data {
+		samples[data.Id] = data.Attributes.Sample
+	}
+
+	now := time.Now()
+	metrics := make([]action_kit_api.Metric, 0)
+	for _, m := range liveMetrics {
+		sample, ok := samples[m.name]
+		if !ok {
+			continue
+		}
+		for _, stat := range m.stats {
+			value, ok := sample[stat]
+			if !ok {
+				continue
+			}
+			if m.unit == "%" {
+				value = value * 100
+			}
+			metrics = append(metrics, action_kit_api.Metric{
+				Name:      new(metricName(m.name)),
+				Metric:    map[string]string{"stat": stat},
+				Timestamp: now,
+				Value:     value,
+			})
+		}
+	}
+	return metrics
+}
+
+func metricWidgets() []action_kit_api.Widget {
+	widgets := make([]action_kit_api.Widget, 0, len(liveMetrics))
+	for _, m := range liveMetrics {
+		widgets = append(widgets, action_kit_api.LineChartWidget{
+			Type:  action_kit_api.ComSteadybitWidgetLineChart,
+			Title: m.title,
+			Identity: action_kit_api.LineChartWidgetIdentityConfig{
+				MetricName: metricName(m.name),
+				From:       "stat",
+				Mode:       action_kit_api.ComSteadybitWidgetLineChartIdentityModeSelect,
+			},
+			Tooltip: new(action_kit_api.LineChartWidgetTooltipConfig{
+				MetricValueTitle:  new(m.title),
+				MetricValueUnit:   new(m.unit),
+				AdditionalContent: []action_kit_api.LineChartWidgetTooltipContent{},
+			}),
+		})
+	}
+	return widgets
+}
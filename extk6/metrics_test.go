@@ -0,0 +1,57 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fetchMetrics(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "http://127.0.0.1:6565/v1/metrics", httpmock.NewStringResponder(200, `{"data": [
+		{"type": "metrics", "id": "http_req_duration", "attributes": {"type": "trend", "contains": "time", "sample": {"avg": 12.5, "max": 40, "med": 10, "min": 2, "p(90)": 30, "p(95)": 35}}},
+		{"type": "metrics", "id": "http_reqs", "attributes": {"type": "counter", "sample": {"count": 100, "rate": 20}}},
+		{"type": "metrics", "id": "http_req_failed", "attributes": {"type": "rate", "sample": {"rate": 0.25}}},
+		{"type": "metrics", "id": "vus", "attributes": {"type": "gauge", "sample": {"value": 5}}},
+		{"type": "metrics", "id": "iterations", "attributes": {"type": "counter", "sample": {"count": 100, "rate": 20}}}
+	]}`))
+	httpmock.RegisterResponder("GET", "http://127.0.0.1:6566/v1/metrics", httpmock.NewErrorResponder(fmt.Errorf("connection refused")))
+	httpmock.RegisterResponder("GET", "http://127.0.0.1:6567/v1/metrics", httpmock.NewStringResponder(200, "invalid json"))
+
+	metrics := fetchMetrics("127.0.0.1:6565")
+
+	values := make(map[string]float64)
+	for _, m := range metrics {
+		values[fmt.Sprintf("%s %s", *m.Name, m.Metric["stat"])] = m.Value
+	}
+	require.Len(t, metrics, 8)
+	assert.Equal(t, map[string]float64{
+		"k6_http_req_duration avg":   12.5,
+		"k6_http_req_duration med":   10,
+		"k6_http_req_duration p(90)": 30,
+		"k6_http_req_duration p(95)": 35,
+		"k6_http_req_duration max":   40,
+		"k6_http_reqs rate":          20,
+		"k6_http_req_failed rate":    25,
+		"k6_vus value":               5,
+	}, values)
+
+	assert.Nil(t, fetchMetrics("127.0.0.1:6566"))
+	assert.Nil(t, fetchMetrics("127.0.0.1:6567"))
+	assert.Nil(t, fetchMetrics(""))
+}
+
+func Test_freeApiAddress(t *testing.T) {
+	address, err := freeApiAddress()
+
+	require.NoError(t, err)
+	assert.Regexp(t, `^127\.0\.0\.1:\d+$`, address)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+)
+
+// outputsParameter lets users select the configured additional outputs, if there are any.
+func outputsParameter(outputs config.Outputs) []action_kit_api.ActionParameter {
+	if len(outputs) == 0 {
+		return nil
+	}
+	options := make([]action_kit_api.ParameterOption, 0, len(outputs))
+	for _, name := range outputs.Names() {
+		options = append(options, action_kit_api.ExplicitParameterOption{Label: name, Value: name})
+	}
+	defaults, _ := json.Marshal(outputs.Defaults())
+	return []action_kit_api.ActionParameter{
+		{
+			Name:         "outputs",
+			Label:        "Outputs",
+			Description:  new("Additional outputs to stream the results to, besides the metrics attached to the experiment."),
+			Type:         action_kit_api.ActionParameterTypeStringArray,
+			Required:     new(false),
+			DefaultValue: new(string(defaults)),
+			Options:      &options,
+			Advanced:     new(true),
+			Order:        new(15),
+		},
+	}
+}
+
+// selectedOutputs returns the names of the outputs to use, the default ones if
+// none were selected.
+func selectedOutputs(runConfig K6LoadTestRunConfig, outputs config.Outputs) ([]string, error) {
+	if runConfig.Outputs == nil {
+		return outputs.Defaults(), nil
+	}
+	selected := make([]string, 0, len(*runConfig.Outputs))
+	for _, name := range *runConfig.Outputs {
+		if _, ok := outputs[name]; !ok {
+			return nil, fmt.Errorf("unknown output '%s'", name)
+		}
+		if !slices.Contains(selected, name) {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// outputArgs returns the k6 flags for the outputs.
+func outputArgs(names []string, outputs config.Outputs) []string {
+	var args []string
+	for _, name := range names {
+		args = append(args, "--out", outputs[name].Output)
+	}
+	return args
+}
+
+// outputsEnv returns the environment variables configuring the outputs. They
+// are resolved when starting k6, as they may contain credentials.
+func outputsEnv(names []string, outputs config.Outputs) []string {
+	var env []string
+	for _, name := range names {
+		keys := make([]string, 0, len(outputs[name].Env))
+		for key := range outputs[name].Env {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			env = append(env, fmt.Sprintf("%s=%s", key, outputs[name].Env[key]))
+		}
+	}
+	return env
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withOutputs(t *testing.T, outputs config.Outputs) {
+	previous := config.Config.Outputs
+	config.Config.Outputs = outputs
+	t.Cleanup(func() { config.Config.Outputs = previous })
+}
+
+func Test_selectedOutputs(t *testing.T) {
+	outputs := config.Outputs{
+		"prometheus": {Output: "experimental-prometheus-rw", Default: true},
+		"csv":        {Output: "csv=/tmp/results.csv"},
+		"statsd":     {Output: "statsd", Default: true},
+	}
+	tests := []struct {
+		name     string
+		selected *[]string
+		want     []string
+		wantErr  string
+	}{
+		{name: "defaults", selected: nil, want: []string{"prometheus", "statsd"}},
+		{name: "none", selected: &[]string{}, want: []string{}},
+		{name: "selected", selected: &[]string{"csv", "csv"}, want: []string{"csv"}},
+		{name: "unknown", selected: &[]string{"influxdb"}, wantErr: "unknown output 'influxdb'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectedOutputs(K6LoadTestRunConfig{Outputs: tt.selected}, outputs)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrepareAddsSelectedOutputs(t *testing.T) {
+	withOutputs(t, config.Outputs{
+		"prometheus": {Output: "experimental-prometheus-rw", Env: map[string]string{"K6_PROMETHEUS_RW_SERVER_URL": "http://prometheus:9090/api/v1/write"}},
+	})
+	action := NewK6LoadTestRunAction()
+
+	state := action.NewEmptyState()
+	result, err := action.Prepare(context.TODO(), &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": "test.js", "outputs": []string{"prometheus"}},
+		ExecutionId: uuid.New(),
+	}))
+	require.NoError(t, err)
+	require.Nil(t, result)
+	assert.Subset(t, state.Command, []string{"--out", "experimental-prometheus-rw"})
+	assert.Equal(t, []string{"prometheus"}, state.Outputs)
+	assert.NotContains(t, state.Command, "http://prometheus:9090/api/v1/write", "the settings are passed when starting")
+
+	state = action.NewEmptyState()
+	result, err = action.Prepare(context.TODO(), &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": "test.js", "outputs": []string{"influxdb"}},
+		ExecutionId: uuid.New(),
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "Invalid outputs: unknown output 'influxdb'.", result.Error.Title)
+}
+
+func TestDescribeListsOutputs(t *testing.T) {
+	withOutputs(t, config.Outputs{"prometheus": {Output: "experimental-prometheus-rw", Default: true}, "csv": {Output: "csv"}})
+
+	description := NewK6LoadTestRunAction().Describe()
+
+	for _, p := range description.Parameters {
+		if p.Name == "outputs" {
+			assert.Equal(t, `["prometheus"]`, *p.DefaultValue)
+			assert.Len(t, *p.Options, 2)
+			return
+		}
+	}
+	assert.Fail(t, "the outputs parameter is missing")
+}
+
+// TestPrometheusRemoteWriteOutput runs k6 with a stand-in for Prometheus receiving the remote write samples.
+func TestPrometheusRemoteWriteOutput(t *testing.T) {
+	if _, err := exec.LookPath("k6"); err != nil {
+		t.Skip("k6 is not installed")
+	}
+	var samples atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/write" && r.Header.Get("Content-Type") == "application/x-protobuf" {
+			samples.Add(1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	withOutputs(t, config.Outputs{
+		"prometheus": {Output: "experimental-prometheus-rw", Env: map[string]string{
+			"K6_PROMETHEUS_RW_SERVER_URL":    receiver.URL + "/api/v1/write",
+			"K6_PROMETHEUS_RW_PUSH_INTERVAL": "500ms",
+		}},
+	})
+	script := writeFile(t, "test.js", []byte(`import { sleep } from 'k6'; export default function () { sleep(0.1); }`))
+	executionId := newExecution(t)
+	require.NoError(t, os.MkdirAll(filepath.Dir(summaryExportFilename(executionId)), 0755))
+	action := NewK6LoadTestRunAction()
+	state := action.NewEmptyState()
+	result, err := action.Prepare(context.TODO(), &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": script, "outputs": []string{"prometheus"}, "vus": 1, "testDuration": 2000},
+		ExecutionId: executionId,
+	}))
+	require.NoError(t, err)
+	require.Nil(t, result)
+
+	_, err = action.Start(context.TODO(), &state)
+	require.NoError(t, err)
+	require.True(t, awaitExit(state.CmdStateID, 30*time.Second), "k6 must finish")
+
+	assert.Positive(t, samples.Load(), "the remote write samples must arrive")
+}
+
+func TestStartPassesOutputSettings(t *testing.T) {
+	withOutputs(t, config.Outputs{"statsd": {Output: "statsd", Env: map[string]string{"K6_STATSD_ADDR": "statsd:8125"}}})
+	state := &K6LoadTestRunState{
+		Command:     []string{"sh", "-c", `echo "statsd at $K6_STATSD_ADDR"`},
+		ExecutionId: newExecution(t),
+		Outputs:     []string{"statsd"},
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(summaryExportFilename(state.ExecutionId)), 0755))
+
+	_, err := startLocal(state, nil)
+	require.NoError(t, err)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	result, err := status(state)
+
+	require.NoError(t, err)
+	var messages []string
+	for _, m := range *result.Messages {
+		messages = append(messages, m.Message)
+	}
+	assert.Contains(t, messages, "statsd at statsd:8125")
+}
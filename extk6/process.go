@@ -0,0 +1,138 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// terminateTimeout is how long to wait for k6 to be gone after SIGTERM was sent.
+	terminateTimeout = 5 * time.Second
+	// killTimeout is how long to wait for k6 to be gone after SIGKILL was sent.
+	killTimeout = 5 * time.Second
+	// groupPollInterval is how often the processes left behind by k6 are checked.
+	groupPollInterval = 100 * time.Millisecond
+)
+
+// exited holds a channel per command state id which is closed as soon as the
+// command has exited and its output has been consumed completely.
+var exited = sync.Map{}
+
+func registerExitChannel(cmdStateId string) chan struct{} {
+	done := make(chan struct{})
+	exited.Store(cmdStateId, done)
+	return done
+}
+
+// awaitExit waits up to timeout for the command to exit and reports whether it
+// did. Commands which are unknown to this extension instance are never reported
+// as exited.
+func awaitExit(cmdStateId string, timeout time.Duration) bool {
+	value, ok := exited.Load(cmdStateId)
+	if !ok {
+		return false
+	}
+	done := value.(chan struct{})
+	// checked first, as select picks randomly if the timeout has passed as well
+	select {
+	case <-done:
+		return true
+	default:
+	}
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// terminateProcess sends SIGINT to the process group of k6, which makes k6 abort
+// the test gracefully: teardown() is executed, the end-of-test summary is printed
+// and all outputs are flushed. If k6 did not exit within gracePeriod, the group
+// is terminated and finally killed. Processes of the group which outlive k6 are
+// killed as well and returned.
+func terminateProcess(cmdStateId string, pid int, gracePeriod time.Duration) []groupProcess {
+	defer exited.Delete(cmdStateId)
+
+	if !awaitExit(cmdStateId, 0) {
+		stopProcessGroup(cmdStateId, pid, gracePeriod)
+	}
+	return killLeftovers(pid)
+}
+
+func stopProcessGroup(cmdStateId string, pid int, gracePeriod time.Duration) {
+	if gracePeriod > 0 {
+		log.Info().Msgf("Interrupting k6 (pid %d), waiting up to %s for it to exit.", pid, gracePeriod)
+		if err := signalGroup(pid, syscall.SIGINT); err != nil {
+			log.Warn().Err(err).Msgf("Failed to interrupt k6 process %d", pid)
+		} else if awaitExit(cmdStateId, gracePeriod) {
+			return
+		}
+
+		log.Warn().Msgf("K6 did not exit within %s, terminating it.", gracePeriod)
+		if err := signalGroup(pid, syscall.SIGTERM); err != nil {
+			log.Warn().Err(err).Msgf("Failed to terminate k6 process %d", pid)
+		} else if awaitExit(cmdStateId, terminateTimeout) {
+			return
+		}
+		log.Warn().Msgf("K6 did not exit within %s, killing it.", terminateTimeout)
+	}
+
+	_ = signalGroup(pid, syscall.SIGKILL)
+	awaitExit(cmdStateId, killTimeout)
+}
+
+// killLeftovers terminates and finally kills the processes left in the process
+// group after k6 has exited, e.g. helpers spawned by extensions, and returns them.
+func killLeftovers(pgid int) []groupProcess {
+	leftovers := groupProcesses(pgid)
+	if len(leftovers) == 0 {
+		return nil
+	}
+	log.Warn().Msgf("K6 left %d processes behind, terminating them: %s", len(leftovers), formatGroupProcesses(leftovers))
+	_ = killGroup(pgid, syscall.SIGTERM)
+	if awaitGroupExit(pgid, terminateTimeout) {
+		return leftovers
+	}
+	_ = killGroup(pgid, syscall.SIGKILL)
+	if !awaitGroupExit(pgid, killTimeout) {
+		log.Error().Msgf("Failed to kill the processes left behind by k6: %s", formatGroupProcesses(groupProcesses(pgid)))
+	}
+	return leftovers
+}
+
+// awaitGroupExit waits up to timeout for all processes of the group to exit and
+// reports whether they did.
+func awaitGroupExit(pgid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for len(groupProcesses(pgid)) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(groupPollInterval)
+	}
+	return true
+}
+
+type groupProcess struct {
+	Pid     int
+	Command string
+}
+
+func formatGroupProcesses(processes []groupProcess) string {
+	formatted := make([]string, 0, len(processes))
+	for _, process := range processes {
+		formatted = append(formatted, fmt.Sprintf("%s (pid %d)", process.Command, process.Pid))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procRoot is where the proc file system is mounted.
+var procRoot = "/proc"
+
+// groupProcesses lists the live processes of the process group pgid, ignoring
+// zombies, which are gone already but not yet reaped by their parent.
+func groupProcesses(pgid int) []groupProcess {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil
+	}
+	var result []groupProcess
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// the command is enclosed in parentheses and may contain spaces and parentheses itself
+		stat := string(content)
+		open, end := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+		if open < 0 || end < open {
+			continue
+		}
+		// fields after the command: state, ppid, pgrp, ...
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 3 || fields[0] == "Z" || fields[0] == "X" {
+			continue
+		}
+		if group, err := strconv.Atoi(fields[2]); err == nil && group == pgid {
+			result = append(result, groupProcess{Pid: pid, Command: stat[open+1 : end]})
+		}
+	}
+	return result
+}
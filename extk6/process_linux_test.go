@@ -0,0 +1,66 @@
+//go:build linux
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeStat(t *testing.T, pid int, stat string) {
+	t.Helper()
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644))
+}
+
+func Test_groupProcesses(t *testing.T) {
+	procRoot = t.TempDir()
+	t.Cleanup(func() { procRoot = "/proc" })
+	writeStat(t, 10, "10 (k6) S 1 10 10 0 -1")
+	writeStat(t, 11, "11 (xk6 (helper)) S 10 10 10 0 -1")
+	writeStat(t, 12, "12 (sleep) Z 11 10 10 0 -1")
+	writeStat(t, 13, "13 (other) S 1 13 13 0 -1")
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "self"), 0o755))
+
+	assert.Equal(t, []groupProcess{{Pid: 10, Command: "k6"}, {Pid: 11, Command: "xk6 (helper)"}}, groupProcesses(10))
+}
+
+func Test_stop_kills_the_process_group_of_k6(t *testing.T) {
+	// the children ignore SIGINT and keep the output open, so that k6 only counts as exited once they are terminated
+	state := startFakeK6(t, `trap 'exit 105' INT; sleep 60 & sh -c 'trap "" INT; sleep 60' & wait`, time.Second)
+	pgid := state.Pid
+	require.GreaterOrEqual(t, len(groupProcesses(pgid)), 3)
+
+	begin := time.Now()
+	result, err := stop(state)
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(begin), time.Second+terminateTimeout)
+	assert.Empty(t, groupProcesses(pgid))
+	assert.NotContains(t, messagesText(result), "processes behind")
+}
+
+func Test_stop_kills_and_reports_processes_left_behind(t *testing.T) {
+	// the children run detached from the output, so they outlive k6
+	state := startFakeK6(t, `sleep 60 >/dev/null 2>&1 & sh -c 'trap "" TERM; while true; do sleep 0.1; done' >/dev/null 2>&1 & exit 0`, 0)
+	pgid := state.Pid
+	require.Eventually(t, func() bool { return awaitExit(state.CmdStateID, 0) }, time.Second, 10*time.Millisecond)
+	require.NotEmpty(t, groupProcesses(pgid))
+
+	result, err := stop(state)
+
+	require.NoError(t, err)
+	assert.Empty(t, groupProcesses(pgid))
+	assert.Regexp(t, `K6 left \d processes behind, which were killed: .*sleep \(pid \d+\)`, messagesText(result))
+}
@@ -0,0 +1,28 @@
+//go:build !unix
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// signalGroup signals only the k6 process, as there are no process groups on
+// other operating systems than Unix, which the extension is only built for in
+// development. Windows supports no other signal than kill.
+func signalGroup(pid int, sig syscall.Signal) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
+
+func killGroup(_ int, _ syscall.Signal) error {
+	return errors.ErrUnsupported
+}
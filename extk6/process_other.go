@@ -0,0 +1,13 @@
+//go:build !linux
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+// groupProcesses is not supported on other operating systems than Linux, which
+// the extension is only built for in development.
+func groupProcesses(_ int) []groupProcess {
+	return nil
+}
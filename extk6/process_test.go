@@ -0,0 +1,99 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startFakeK6(t *testing.T, script string, gracePeriod time.Duration, args ...string) *K6LoadTestRunState {
+	t.Helper()
+	state := &K6LoadTestRunState{
+		Command:         append([]string{"sh", "-c", script}, args...),
+		ExecutionId:     uuid.New(),
+		StopGracePeriod: gracePeriod,
+	}
+	folder := fmt.Sprintf("/tmp/steadybit/%v", state.ExecutionId)
+	require.NoError(t, os.MkdirAll(folder, 0755))
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	_, err := start(state, nil)
+	require.NoError(t, err)
+	// give the shell time to install its traps
+	time.Sleep(200 * time.Millisecond)
+	return state
+}
+
+func messagesText(result *action_kit_api.StopResult) string {
+	var sb strings.Builder
+	for _, m := range *result.Messages {
+		sb.WriteString(m.Message)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func Test_stop_interrupts_k6_and_waits_for_teardown(t *testing.T) {
+	state := startFakeK6(t, `trap 'echo teardown done; exit 105' INT; while true; do sleep 0.1; done`, 10*time.Second)
+
+	begin := time.Now()
+	result, err := stop(state)
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(begin), 5*time.Second)
+	assert.Contains(t, messagesText(result), "teardown done")
+	assert.NotContains(t, messagesText(result), "K6 run failed")
+}
+
+func Test_stop_reports_external_abort_not_caused_by_stop(t *testing.T) {
+	state := startFakeK6(t, `exit 105`, 10*time.Second)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+
+	result, err := stop(state)
+
+	require.NoError(t, err)
+	assert.Contains(t, messagesText(result), "K6 run failed with exit code 105")
+}
+
+func Test_stop_kills_k6_after_grace_period(t *testing.T) {
+	state := startFakeK6(t, `trap '' INT; while true; do sleep 0.1; done`, 500*time.Millisecond)
+
+	begin := time.Now()
+	_, err := stop(state)
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(begin), 500*time.Millisecond)
+	assert.False(t, awaitExit(state.CmdStateID, 0), "exit channel must be released")
+}
+
+func Test_stop_kills_k6_immediately_without_grace_period(t *testing.T) {
+	state := startFakeK6(t, `trap '' INT; while true; do sleep 0.1; done`, 0)
+
+	begin := time.Now()
+	_, err := stop(state)
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(begin), killTimeout)
+}
+
+func Test_awaitExit_reports_exit_without_timeout(t *testing.T) {
+	id := uuid.NewString()
+	close(registerExitChannel(id))
+	defer exited.Delete(id)
+
+	for i := 0; i < 1000; i++ {
+		require.True(t, awaitExit(id, 0))
+	}
+	assert.False(t, awaitExit(uuid.NewString(), 0))
+}
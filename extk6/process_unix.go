@@ -0,0 +1,27 @@
+//go:build unix
+
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"errors"
+	"syscall"
+)
+
+// signalGroup sends sig to the process group led by k6, falling back to the k6
+// process only if it is not a group leader, e.g. if started by a previous version.
+func signalGroup(pid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return syscall.Kill(pid, sig)
+	}
+	return err
+}
+
+// killGroup sends sig to all processes of the process group pgid.
+func killGroup(pgid int, sig syscall.Signal) error {
+	return syscall.Kill(-pgid, sig)
+}
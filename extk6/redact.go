@@ -0,0 +1,116 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/steadybit/extension-k6/config"
+)
+
+const redacted = "***"
+
+// minSecretLength is the minimum length of secret values which are masked in k6's
+// output. Shorter values would mask arbitrary text without protecting anything.
+const minSecretLength = 4
+
+// redactors holds a redactor per command state id for as long as k6 is running.
+var redactors = sync.Map{}
+
+// secretEnvironments holds the secret `--env KEY=VALUE` arguments per execution
+// id from prepare until k6 is started, as the action state is returned to the
+// agent and must not contain their values.
+var secretEnvironments = sync.Map{}
+
+// takeSecretEnvArgs returns the secret `--env` arguments of the run and forgets them.
+func takeSecretEnvArgs(state *K6LoadTestRunState) ([]string, error) {
+	if len(state.SecretEnvironmentKeys) == 0 {
+		return nil, nil
+	}
+	args, ok := secretEnvironments.LoadAndDelete(state.ExecutionId.String())
+	if !ok {
+		return nil, errors.New("their values are unknown to this extension instance, e.g. as it was restarted after the load test was prepared")
+	}
+	return args.([]string), nil
+}
+
+type redactor struct {
+	secrets []string
+}
+
+// newRedactor collects the values of all secret `--env KEY=VALUE` entries of the
+// command. Entries are secret if their key is listed in secretKeys or matches the
+// configured secret key pattern.
+func newRedactor(command []string, secretKeys []string) *redactor {
+	r := &redactor{}
+	for i := 1; i < len(command); i++ {
+		if command[i-1] != "--env" {
+			continue
+		}
+		key, value, found := strings.Cut(command[i], "=")
+		if found && len(value) >= minSecretLength && isSecretKey(key, secretKeys) {
+			r.secrets = append(r.secrets, value)
+		}
+	}
+	// longer secrets first, so that a secret containing another is masked completely
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+	return r
+}
+
+func isSecretKey(key string, secretKeys []string) bool {
+	if slices.Contains(secretKeys, key) {
+		return true
+	}
+	if config.Config.SecretEnvironmentKeyPattern == "" {
+		return false
+	}
+	matched, _ := regexp.MatchString(config.Config.SecretEnvironmentKeyPattern, key)
+	return matched
+}
+
+func getRedactor(cmdStateId string) *redactor {
+	if r, ok := redactors.Load(cmdStateId); ok {
+		return r.(*redactor)
+	}
+	return &redactor{}
+}
+
+func (r *redactor) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+func (r *redactor) redactLines(lines []string) []string {
+	if len(r.secrets) == 0 {
+		return lines
+	}
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = r.redact(line)
+	}
+	return result
+}
+
+// redactCommand masks the values of secret `--env KEY=VALUE` entries regardless
+// of their length.
+func redactCommand(command []string, secretKeys []string) []string {
+	result := slices.Clone(command)
+	for i := 1; i < len(result); i++ {
+		if result[i-1] != "--env" {
+			continue
+		}
+		if key, _, found := strings.Cut(result[i], "="); found && isSecretKey(key, secretKeys) {
+			result[i] = key + "=" + redacted
+		}
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_redactCommand(t *testing.T) {
+	config.Config.SecretEnvironmentKeyPattern = "(?i)password"
+	defer func() { config.Config.SecretEnvironmentKeyPattern = "" }()
+
+	command := []string{"k6", "run", "test.js", "--env", "HOST=example.com", "--env", "DB_PASSWORD=s3cr3t!", "--env", "KEY=abc", "--env", "OTHER=x=y"}
+
+	redactedCommand := redactCommand(command, []string{"KEY"})
+
+	assert.Equal(t, []string{"k6", "run", "test.js", "--env", "HOST=example.com", "--env", "DB_PASSWORD=***", "--env", "KEY=***", "--env", "OTHER=x=y"}, redactedCommand)
+	assert.Equal(t, "DB_PASSWORD=s3cr3t!", command[6], "the command itself must not be modified")
+}
+
+func Test_redactor_masks_secret_values_in_output(t *testing.T) {
+	config.Config.SecretEnvironmentKeyPattern = "(?i)token"
+	defer func() { config.Config.SecretEnvironmentKeyPattern = "" }()
+
+	r := newRedactor([]string{"k6", "run", "--env", "API_TOKEN=abcd1234", "--env", "USER=admin", "--env", "PIN=123", "--env", "LONG_TOKEN=abcd12345678"}, []string{"PIN"})
+
+	assert.Equal(t, []string{
+		"level=info msg=\"calling with ***\"\n",
+		"level=info msg=\"long *** and user admin\"\n",
+		"pin 123 is too short to be masked\n",
+	}, r.redactLines([]string{
+		"level=info msg=\"calling with abcd1234\"\n",
+		"level=info msg=\"long abcd12345678 and user admin\"\n",
+		"pin 123 is too short to be masked\n",
+	}))
+}
+
+func Test_stop_redacts_log_and_messages(t *testing.T) {
+	config.Config.SecretEnvironmentKeyPattern = "(?i)password"
+	defer func() { config.Config.SecretEnvironmentKeyPattern = "" }()
+
+	// the fake k6 prints the value of the env entry passed as its first argument
+	state := startFakeK6(t, `echo "connecting with ${1#*=}"; while true; do sleep 0.1; done`, 0, "--env", "DB_PASSWORD=hunter22")
+
+	result, err := stop(state)
+
+	require.NoError(t, err)
+	assert.Contains(t, messagesText(result), "connecting with ***")
+	log, err := os.ReadFile(fmt.Sprintf("/tmp/steadybit/%v/k6_log.txt", state.ExecutionId))
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "connecting with ***")
+	assert.NotContains(t, string(log), "hunter22")
+}
+
+func Test_secret_environment_is_kept_out_of_the_state(t *testing.T) {
+	recorded := filepath.Join(t.TempDir(), "args")
+	state := &K6LoadTestRunState{}
+	result, err := prepare(state, action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{
+			"secretEnvironment": []map[string]string{{"key": "DB_PASSWORD", "value": "hunter22"}},
+		},
+		ExecutionId: newExecution(t),
+	}, []string{"sh", "-c", fmt.Sprintf(`printf '%%s\n' "$@" > %s`, recorded), "k6"})
+	require.NoError(t, err)
+	require.Nil(t, result)
+	require.NoError(t, os.MkdirAll(fmt.Sprintf("/tmp/steadybit/%v", state.ExecutionId), 0755))
+
+	persisted, err := json.Marshal(state)
+	require.NoError(t, err)
+	assert.NotContains(t, string(persisted), "hunter22")
+	assert.Equal(t, []string{"DB_PASSWORD"}, state.SecretEnvironmentKeys)
+
+	_, err = start(state, nil)
+	require.NoError(t, err)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	args, err := os.ReadFile(recorded)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "--env\nDB_PASSWORD=hunter22\n")
+}
+
+func Test_start_fails_without_the_values_of_secret_environment(t *testing.T) {
+	state := &K6LoadTestRunState{
+		Command:               []string{"sh", "-c", "true"},
+		ExecutionId:           uuid.New(),
+		SecretEnvironmentKeys: []string{"DB_PASSWORD"},
+	}
+
+	_, err := start(state, nil)
+
+	assert.ErrorContains(t, err, "Failed to pass the secret environment variables to k6")
+	assert.Empty(t, state.CmdStateID)
+}
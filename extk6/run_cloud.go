@@ -5,21 +5,33 @@
 package extk6
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
 	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-k6/k6cloud"
 	extension_kit "github.com/steadybit/extension-kit"
 	"github.com/steadybit/extension-kit/extconversion"
+	"github.com/steadybit/extension-kit/extutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// cloudPollTimeout limits the requests polling the cloud test run in a status request.
+const cloudPollTimeout = 3 * time.Second
+
 type k6LoadTestCloudAction struct {
-	baseUrl string
+	loadZonesMutex sync.Mutex
+	// loadZones caches the load zones available to the stack of a credential once they are known.
+	loadZones map[string][]k6cloud.LoadZone
+	// fetchingLoadZones marks the credentials whose load zones are fetched in the background.
+	fetchingLoadZones map[string]bool
 }
 
 // Make sure action implements all required interfaces
@@ -38,7 +50,64 @@ func (l *k6LoadTestCloudAction) NewEmptyState() K6LoadTestRunState {
 }
 
 func (l *k6LoadTestCloudAction) Describe() action_kit_api.ActionDescription {
-	return *getActionDescription(fmt.Sprintf("%s.cloud", actionIdPrefix), "K6 Cloud", "Execute a K6 load using K6 Cloud.", nil)
+	description := *getActionDescription(fmt.Sprintf("%s.cloud", actionIdPrefix), "K6 Cloud", "Execute a K6 load using K6 Cloud.", nil)
+	description.Parameters = append(description.Parameters, credentialParameter(config.CloudCredentialNames(), config.DefaultCloudCredentialName()))
+	description.Parameters = append(description.Parameters, cloudParameters(l.knownLoadZones(config.DefaultCloudCredentialName()))...)
+	return description
+}
+
+// knownLoadZones returns the cached load zones of the credential without
+// blocking. If they are not known yet, they are fetched in the background, to be
+// offered by the next description.
+func (l *k6LoadTestCloudAction) knownLoadZones(credential string) []k6cloud.LoadZone {
+	l.loadZonesMutex.Lock()
+	defer l.loadZonesMutex.Unlock()
+	if loadZones, ok := l.loadZones[credential]; ok || l.fetchingLoadZones[credential] {
+		return loadZones
+	}
+	if l.fetchingLoadZones == nil {
+		l.fetchingLoadZones = make(map[string]bool)
+	}
+	l.fetchingLoadZones[credential] = true
+	go func() {
+		l.availableLoadZones(credential)
+		l.loadZonesMutex.Lock()
+		defer l.loadZonesMutex.Unlock()
+		delete(l.fetchingLoadZones, credential)
+	}()
+	return nil
+}
+
+// availableLoadZones lists the load zones available to the stack of the
+// credential, or none if they cannot be fetched.
+func (l *k6LoadTestCloudAction) availableLoadZones(credential string) []k6cloud.LoadZone {
+	l.loadZonesMutex.Lock()
+	loadZones, ok := l.loadZones[credential]
+	l.loadZonesMutex.Unlock()
+	if ok {
+		return loadZones
+	}
+
+	client, err := cloudClient(credential)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get the available K6 cloud load zones.")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	loadZones, err = client.GetLoadZones(ctx)
+	if err != nil || len(loadZones) == 0 {
+		log.Warn().Err(err).Msg("Failed to get the available K6 cloud load zones.")
+		return nil
+	}
+
+	l.loadZonesMutex.Lock()
+	defer l.loadZonesMutex.Unlock()
+	if l.loadZones == nil {
+		l.loadZones = make(map[string][]k6cloud.LoadZone)
+	}
+	l.loadZones[credential] = loadZones
+	return loadZones
 }
 
 func (l *k6LoadTestCloudAction) Prepare(_ context.Context, state *K6LoadTestRunState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
@@ -46,83 +115,300 @@ func (l *k6LoadTestCloudAction) Prepare(_ context.Context, state *K6LoadTestRunS
 	if err := extconversion.Convert(request.Config, &runConfig); err != nil {
 		return nil, extension_kit.ToError("Failed to unmarshal the runConfig.", err)
 	}
-	command := []string{"k6", "cloud", "run", runConfig.File}
+	script, err := prepareScript(state, request, runConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if result := prepareCloudCredential(state, runConfig); result != nil {
+		return result, nil
+	}
+
+	var availableLoadZones []k6cloud.LoadZone
+	if len(runConfig.LoadZones) > 0 {
+		availableLoadZones = l.availableLoadZones(state.CloudCredential)
+	}
+	options, err := cloudOptions(runConfig, availableLoadZones)
+	if err != nil {
+		return &action_kit_api.PrepareResult{
+			Error: &action_kit_api.ActionKitError{
+				Status: extutil.Ptr(action_kit_api.Errored),
+				Title:  fmt.Sprintf("Invalid cloud options: %s.", err),
+			},
+		}, nil
+	}
+	if len(options) > 0 {
+		if strings.HasSuffix(strings.ToLower(script), ".tar") {
+			return &action_kit_api.PrepareResult{
+				Error: &action_kit_api.ActionKitError{
+					Status: extutil.Ptr(action_kit_api.Errored),
+					Title:  "The project, test name and load zones cannot be overridden for k6 archives, set them in the script's cloud options instead.",
+				},
+			}, nil
+		}
+		if script, err = writeCloudWrapper(state.WorkingDir, script, options); err != nil {
+			return nil, extension_kit.ToError("Failed to override the script's cloud options.", err)
+		}
+	}
+
+	command := []string{"k6", "cloud", "run", script}
 	return prepare(state, request, command)
 }
 
 func (l *k6LoadTestCloudAction) Start(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StartResult, error) {
-	log.Info().Msg("Using K6 cloud with the configured API token")
-	return start(state, config.Config.CloudApiToken)
+	env, err := cloudCredentialEnv(state.CloudCredential)
+	if err != nil {
+		return nil, err
+	}
+	return start(state, env)
 }
 
-func (l *k6LoadTestCloudAction) Status(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StatusResult, error) {
-	return status(state)
+// prepareCloudCredential keeps the name of the selected credential in the state,
+// returning a result with the error if it is unknown. The token is only resolved
+// when starting.
+func prepareCloudCredential(state *K6LoadTestRunState, runConfig K6LoadTestRunConfig) *action_kit_api.PrepareResult {
+	credential := runConfig.Credential
+	if credential == "" {
+		credential = config.DefaultCloudCredentialName()
+	}
+	if _, err := config.GetCloudCredential(credential); err != nil {
+		return &action_kit_api.PrepareResult{
+			Error: &action_kit_api.ActionKitError{
+				Status: extutil.Ptr(action_kit_api.Errored),
+				Title:  fmt.Sprintf("Invalid cloud credential: %s.", err),
+			},
+		}
+	}
+	state.CloudCredential = credential
+	return nil
 }
 
-func (l *k6LoadTestCloudAction) Stop(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StopResult, error) {
-	if state.CloudRunId != "" {
-		running, err := isCloudRunStillRunning(state.CloudRunId)
-		if err != nil {
-			return nil, err
-		}
-		if running {
-			err = stopCloudRun(state.CloudRunId)
-			if err != nil {
-				return nil, err
-			}
-		}
+// cloudCredentialEnv returns the environment variables authenticating k6 with the named credential.
+func cloudCredentialEnv(name string) ([]string, error) {
+	credential, err := cloudCredential(name)
+	if err != nil {
+		return nil, extension_kit.ToError("Failed to resolve the cloud credential.", err)
+	}
+	log.Info().Msgf("Using K6 cloud with the credential %s", name)
+	env := []string{fmt.Sprintf("K6_CLOUD_TOKEN=%s", credential.Token)}
+	if credential.StackId != "" {
+		env = append(env, fmt.Sprintf("K6_CLOUD_STACK_ID=%s", credential.StackId))
 	}
+	return env, nil
+}
 
-	return stop(state)
+func (l *k6LoadTestCloudAction) Status(ctx context.Context, state *K6LoadTestRunState) (*action_kit_api.StatusResult, error) {
+	result, err := status(state)
+	if state.CloudRunId == "" {
+		return result, err
+	}
+	if err != nil {
+		// the cloud test run can be followed without the local k6 process
+		log.Warn().Err(err).Msg("Failed to get the status of the local k6 process.")
+		result = &action_kit_api.StatusResult{Messages: &[]action_kit_api.Message{}}
+	}
+	client, err := cloudClient(state.CloudCredential)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to get K6 cloud test run %s.", state.CloudRunId)
+		*result.Messages = append(*result.Messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Warn),
+			Message: fmt.Sprintf("Failed to get the status of the K6 cloud test run %s: %s", state.CloudRunId, err.Error()),
+		})
+		return result, nil
+	}
+	// status is polled every few seconds, so a slow or failing API is not waited for
+	ctx, cancel := context.WithTimeout(ctx, cloudPollTimeout)
+	defer cancel()
+	pollCloudRun(ctx, client.WithoutRetries(), state, result)
+	return result, nil
 }
 
-func isCloudRunStillRunning(cloudRunId string) (bool, error) {
-	res, err := http.Get(fmt.Sprintf("%s/loadtests/v2/runs/%s", config.Config.CloudApiBaseUrl, cloudRunId))
+// pollCloudRun reports the state of the cloud test run. Once the run id is known,
+// the cloud test run decides whether the action has completed, as the local k6
+// process only follows it and might be gone.
+func pollCloudRun(ctx context.Context, client *k6cloud.Client, state *K6LoadTestRunState, result *action_kit_api.StatusResult) {
+	run, err := client.GetTestRun(ctx, state.CloudRunId)
 	if err != nil {
-		return false, fmt.Errorf("failed to get k6 cloud status: %s", err.Error())
-	} else if !(res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices) {
-		return false, fmt.Errorf("failed to get k6 cloud status: %d - %s", res.StatusCode, res.Status)
+		log.Warn().Err(err).Msgf("Failed to get K6 cloud test run %s.", state.CloudRunId)
+		*result.Messages = append(*result.Messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Warn),
+			Message: fmt.Sprintf("Failed to get the status of the K6 cloud test run %s: %s", state.CloudRunId, err.Error()),
+		})
+		return
 	}
-	defer func() { _ = res.Body.Close() }()
 
-	var status StatusResponse
-	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
-		return false, fmt.Errorf("failed to get k6 cloud status: %s", err.Error())
+	if run.Status != state.CloudRunStatus {
+		state.CloudRunStatus = run.Status
+		message := fmt.Sprintf("K6 cloud test run %s is %s.", state.CloudRunId, strings.ReplaceAll(run.Status, "_", " "))
+		if run.StatusDetails.Message != "" {
+			message = fmt.Sprintf("%s %s", message, run.StatusDetails.Message)
+		}
+		*result.Messages = append(*result.Messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: message,
+			Fields:  extutil.Ptr(action_kit_api.MessageFields{"cloudRunId": state.CloudRunId, "status": run.Status}),
+		})
 	}
 
-	return status.K6Run.RunStatus < 3, nil
+	if run.Status == k6cloud.StatusRunning {
+		if vus, err := client.QueryAggregate(ctx, state.CloudRunId, "vus", "max"); err != nil {
+			log.Debug().Err(err).Msgf("Failed to get the VUs of K6 cloud test run %s.", state.CloudRunId)
+		} else if vus != nil {
+			*result.Messages = append(*result.Messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("K6 cloud test run %s has reached %d VUs.", state.CloudRunId, int(*vus)),
+				Fields:  extutil.Ptr(action_kit_api.MessageFields{"cloudRunId": state.CloudRunId, "vus": strconv.Itoa(int(*vus))}),
+			})
+		}
+	}
+
+	result.Completed = run.Status == k6cloud.StatusCompleted || run.Status == k6cloud.StatusAborted
+	result.Error = nil
+	if result.Completed {
+		result.Error = cloudRunError(run)
+		resultText := "none"
+		if run.Result != nil {
+			resultText = *run.Result
+		}
+		*result.Messages = append(*result.Messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: fmt.Sprintf("K6 cloud test run %s has finished with result %s.", state.CloudRunId, resultText),
+			Fields:  extutil.Ptr(action_kit_api.MessageFields{"cloudRunId": state.CloudRunId, "result": resultText}),
+		})
+	}
 }
 
-func stopCloudRun(cloudRunId string) error {
-	r, err := http.NewRequest("POST", fmt.Sprintf("%s/loadtests/v2/runs/%s/stop", config.Config.CloudApiBaseUrl, cloudRunId), bytes.NewBufferString("{}"))
-	if err != nil {
-		return fmt.Errorf("failed to stop k6 cloud: %w", err)
+// cloudRunError maps the result of a finished cloud test run to an error, or nil
+// if it has passed.
+func cloudRunError(run *k6cloud.TestRun) *action_kit_api.ActionKitError {
+	var detail *string
+	if run.StatusDetails.Message != "" {
+		detail = extutil.Ptr(run.StatusDetails.Message)
+	}
+	if run.Status == k6cloud.StatusAborted {
+		status := action_kit_api.Errored
+		if strings.Contains(run.StatusDetails.Type, "threshold") {
+			status = action_kit_api.Failed
+		}
+		return &action_kit_api.ActionKitError{
+			Status: extutil.Ptr(status),
+			Title:  fmt.Sprintf("The k6 cloud test run was aborted (%s).", run.StatusDetails.Type),
+			Detail: detail,
+		}
+	}
+	if run.Result == nil || *run.Result == k6cloud.ResultPassed {
+		return nil
+	}
+	if *run.Result == k6cloud.ResultFailed {
+		return &action_kit_api.ActionKitError{
+			Status: extutil.Ptr(exitCodes[exitCodeCloudTestRunFailed].status),
+			Title:  exitCodes[exitCodeCloudTestRunFailed].title,
+			Detail: detail,
+		}
+	}
+	return &action_kit_api.ActionKitError{
+		Status: extutil.Ptr(action_kit_api.Errored),
+		Title:  "The k6 cloud test run has errored.",
+		Detail: detail,
+	}
+}
+
+func (l *k6LoadTestCloudAction) Stop(ctx context.Context, state *K6LoadTestRunState) (*action_kit_api.StopResult, error) {
+	var messages []action_kit_api.Message
+	var client *k6cloud.Client
+	if state.CloudRunId != "" {
+		var err error
+		if client, err = cloudClient(state.CloudCredential); err != nil {
+			log.Warn().Err(err).Msgf("Failed to abort K6 cloud test run %s.", state.CloudRunId)
+			messages = append(messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Error),
+				Message: fmt.Sprintf("Failed to abort the K6 cloud test run %s: %s", state.CloudRunId, err.Error()),
+			})
+		} else if err := abortCloudRun(ctx, client, state.CloudRunId); err != nil {
+			// the local k6 process must be stopped anyway, k6 aborts the run itself when interrupted
+			log.Warn().Err(err).Msgf("Failed to abort K6 cloud test run %s.", state.CloudRunId)
+			messages = append(messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Error),
+				Message: fmt.Sprintf("Failed to abort the K6 cloud test run %s: %s", state.CloudRunId, err.Error()),
+			})
+		}
 	}
-	r.Header.Add("Content-Type", "application/json")
-	r.Header.Add("Authorization", fmt.Sprintf("token %s", config.Config.CloudApiToken))
 
-	log.Info().Msgf("Stop K6 cloud at %s", r.URL)
-	res, err := http.DefaultClient.Do(r)
+	result, err := stop(state)
 	if err != nil {
-		return fmt.Errorf("failed to stop k6 cloud: %w", err)
+		return nil, err
+	}
+	if result == nil {
+		result = &action_kit_api.StopResult{}
+	}
+	if result.Messages != nil {
+		messages = append(messages, *result.Messages...)
+	}
+	var artifacts []action_kit_api.Artifact
+	if result.Artifacts != nil {
+		artifacts = *result.Artifacts
 	}
 
-	defer func() { _ = res.Body.Close() }()
-	if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
-		log.Info().Msg("K6 cloud stop requested.")
-	} else {
-		log.Info().Msgf("K6 cloud stop responded with HTTP Status %s.", res.Status)
+	if client != nil {
+		results, warnings := fetchCloudResults(ctx, client, state)
+		messages = append(messages, warnings...)
+		messages = append(messages, cloudResultsToMessages(results)...)
+		filename := cloudResultsFilename(state)
+		if err := writeCloudResults(filename, results); err != nil {
+			log.Warn().Err(err).Msg("Failed to write the K6 cloud results.")
+		} else if artifacts, err = appendFileArtifact(artifacts, filename, "$(experimentKey)_$(executionId)_k6_cloud_results.json"); err != nil {
+			return nil, err
+		}
 	}
-	return nil
+
+	result.Messages = &messages
+	result.Artifacts = &artifacts
+	return result, nil
 }
 
-type StatusResponse struct {
-	K6Run RunStatus `json:"k6-run"`
+// cloudCredential resolves the named credential, the token is read at the time of use.
+func cloudCredential(name string) (config.CloudCredential, error) {
+	if name == "" {
+		// states prepared before credentials could be selected
+		name = config.DefaultCloudCredentialName()
+	}
+	return config.GetCloudCredential(name)
 }
 
-type RunStatus struct {
-	// Values: https://k6.io/docs/cloud/cloud-reference/cloud-rest-api/test-runs/#read-load-test-run
-	RunStatus int    `json:"run_status"`
-	Started   string `json:"started"`
-	Ended     string `json:"ended"`
+// cloudClient returns a client for the cloud api using the named credential.
+func cloudClient(name string) (*k6cloud.Client, error) {
+	credential, err := cloudCredential(name)
+	if err != nil {
+		return nil, err
+	}
+	return newCloudClient(credential), nil
+}
+
+func newCloudClient(credential config.CloudCredential) *k6cloud.Client {
+	return k6cloud.NewClient(config.Config.CloudApiBaseUrl, credential.Token, credential.StackId)
+}
+
+// abortCloudRun aborts the cloud test run unless it has finished already.
+func abortCloudRun(ctx context.Context, client *k6cloud.Client, cloudRunId string) error {
+	run, err := client.GetTestRun(ctx, cloudRunId)
+	if err != nil {
+		return fmt.Errorf("failed to get k6 cloud test run: %w", err)
+	}
+	if run.IsFinished() {
+		log.Info().Msgf("K6 cloud test run %s has already finished with status %s.", cloudRunId, run.Status)
+		return nil
+	}
+
+	log.Info().Msgf("Aborting K6 cloud test run %s.", cloudRunId)
+	err = client.AbortTestRun(ctx, cloudRunId)
+	var httpErr *k6cloud.HttpError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict {
+		// the test run has finished in the meantime
+		log.Info().Msgf("K6 cloud test run %s cannot be aborted anymore.", cloudRunId)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to abort k6 cloud test run: %w", err)
+	}
+	log.Info().Msg("K6 cloud abort requested.")
+	return nil
 }
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-k6/config"
+	extension_kit "github.com/steadybit/extension-kit"
+	"github.com/steadybit/extension-kit/extconversion"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// k6LoadTestCloudOutputAction runs the load test on the extension's location, so
+// that it can reach internal services, while streaming the results to Grafana Cloud k6.
+type k6LoadTestCloudOutputAction struct{}
+
+// Make sure action implements all required interfaces
+var (
+	_ action_kit_sdk.Action[K6LoadTestRunState]           = (*k6LoadTestCloudOutputAction)(nil)
+	_ action_kit_sdk.ActionWithStatus[K6LoadTestRunState] = (*k6LoadTestCloudOutputAction)(nil)
+	_ action_kit_sdk.ActionWithStop[K6LoadTestRunState]   = (*k6LoadTestCloudOutputAction)(nil)
+)
+
+func NewK6LoadTestCloudOutputAction() action_kit_sdk.Action[K6LoadTestRunState] {
+	return &k6LoadTestCloudOutputAction{}
+}
+
+func (l *k6LoadTestCloudOutputAction) NewEmptyState() K6LoadTestRunState {
+	return K6LoadTestRunState{}
+}
+
+func (l *k6LoadTestCloudOutputAction) Describe() action_kit_api.ActionDescription {
+	hint := action_kit_api.ActionHint{
+		Content: "Please note that load tests are executed by the k6 extension participating in the experiment, consuming resources of the system that it is installed in. Only the results are streamed to Grafana Cloud k6.",
+		Type:    action_kit_api.HintWarning,
+	}
+	description := *getActionDescription(fmt.Sprintf("%s.run-cloud-output", actionIdPrefix), "K6 local with cloud output", "Execute a K6 load test and stream its results to K6 Cloud.", &hint)
+	description.Parameters = append(description.Parameters, credentialParameter(config.CloudCredentialNames(), config.DefaultCloudCredentialName()))
+	description.Parameters = append(description.Parameters, outputsParameter(config.Config.Outputs)...)
+	description.Widgets = new(metricWidgets())
+	addLocationSelection(&description)
+	return description
+}
+
+func (l *k6LoadTestCloudOutputAction) Prepare(_ context.Context, state *K6LoadTestRunState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	var runConfig K6LoadTestRunConfig
+	if err := extconversion.Convert(request.Config, &runConfig); err != nil {
+		return nil, extension_kit.ToError("Failed to unmarshal the runConfig.", err)
+	}
+	if result := prepareCloudCredential(state, runConfig); result != nil {
+		return result, nil
+	}
+	return prepareLocal(state, request, "cloud")
+}
+
+func (l *k6LoadTestCloudOutputAction) Start(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StartResult, error) {
+	env, err := cloudCredentialEnv(state.CloudCredential)
+	if err != nil {
+		return nil, err
+	}
+	return startLocal(state, env)
+}
+
+func (l *k6LoadTestCloudOutputAction) Status(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StatusResult, error) {
+	if state.QueuedAt != nil {
+		env, err := cloudCredentialEnv(state.CloudCredential)
+		if err != nil {
+			return nil, err
+		}
+		return statusQueued(state, localEnv(state, env))
+	}
+	cloudRunId := state.CloudRunId
+	result, err := statusLocal(state)
+	if err != nil {
+		return nil, err
+	}
+	if state.CloudRunId != cloudRunId {
+		*result.Messages = append(*result.Messages, cloudRunLinkMessage(state))
+	}
+	return result, nil
+}
+
+func (l *k6LoadTestCloudOutputAction) Stop(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StopResult, error) {
+	result, err := stop(state)
+	if err != nil || state.CloudRunId == "" {
+		return result, err
+	}
+	if result == nil {
+		result = &action_kit_api.StopResult{}
+	}
+	messages := []action_kit_api.Message{cloudRunLinkMessage(state)}
+	if result.Messages != nil {
+		messages = append(*result.Messages, messages...)
+	}
+	result.Messages = &messages
+	return result, nil
+}
+
+func cloudRunLinkMessage(state *K6LoadTestRunState) action_kit_api.Message {
+	return action_kit_api.Message{
+		Level:   extutil.Ptr(action_kit_api.Info),
+		Message: fmt.Sprintf("K6 cloud test run: %s", state.CloudRunUrl),
+		Fields:  extutil.Ptr(action_kit_api.MessageFields{"cloudRunId": state.CloudRunId, "url": state.CloudRunUrl}),
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-k6/config"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudOutputPrepareStreamsToCloud(t *testing.T) {
+	withCloudCredential(t)
+	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config:      map[string]any{"file": "test.js", "vus": 5},
+		ExecutionId: uuid.New(),
+	})
+	action := NewK6LoadTestCloudOutputAction()
+	state := action.NewEmptyState()
+
+	result, err := action.Prepare(context.TODO(), &state, request)
+
+	require.NoError(t, err)
+	require.Nil(t, result)
+	assert.Equal(t, []string{"k6", "run", "test.js"}, state.Command[:3])
+	assert.Subset(t, state.Command, []string{"--out", "cloud", "--vus", "5"})
+	assert.Contains(t, state.Command, fmt.Sprintf("json=/tmp/steadybit/%v/metrics.json", request.ExecutionId))
+	assert.Equal(t, config.DefaultCloudCredential, state.CloudCredential)
+	assert.Empty(t, state.ApiAddress)
+}
+
+func TestCloudOutputLinksCloudRun(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	config.Config.CloudStackId = "4242"
+	t.Cleanup(func() { config.Config.CloudStackId = "" })
+	action := NewK6LoadTestCloudOutputAction()
+	state := action.NewEmptyState()
+	state.ExecutionId = uuid.New()
+	state.CloudCredential = config.DefaultCloudCredential
+	state.Command = []string{"sh", "-c", `echo "stack $K6_CLOUD_STACK_ID, token ${K6_CLOUD_TOKEN:+set}"; echo "     output: cloud (https://app.k6.io/runs/42)"; while true; do sleep 0.1; done`}
+	folder := fmt.Sprintf("/tmp/steadybit/%v", state.ExecutionId)
+	require.NoError(t, os.MkdirAll(folder, 0755))
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	_, err := action.Start(context.TODO(), &state)
+	require.NoError(t, err)
+	time.Sleep(200 * time.Millisecond)
+	status, err := action.(*k6LoadTestCloudOutputAction).Status(context.TODO(), &state)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, m := range *status.Messages {
+		messages = append(messages, m.Message)
+	}
+	assert.Contains(t, messages, "stack 4242, token set")
+	assert.Contains(t, messages, "K6 cloud test run: https://app.k6.io/runs/42")
+	assert.Equal(t, "42", state.CloudRunId)
+
+	result, err := action.(*k6LoadTestCloudOutputAction).Stop(context.TODO(), &state)
+	require.NoError(t, err)
+	assert.Contains(t, messagesText(result), "K6 cloud test run: https://app.k6.io/runs/42\n")
+}
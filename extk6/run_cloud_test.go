@@ -14,11 +14,22 @@ import (
 	"github.com/steadybit/extension-kit/extutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
+// withCloudCredential configures the default cloud credential for the test.
+func withCloudCredential(t *testing.T) {
+	token := config.Config.CloudApiToken
+	config.Config.CloudApiToken = "test-token"
+	t.Cleanup(func() { config.Config.CloudApiToken = token })
+}
+
 func TestPrepareExtractsState(t *testing.T) {
 	// Given
+	withCloudCredential(t)
 	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
 		Config: map[string]any{
 			"duration": 1000 * 60,
@@ -29,6 +40,8 @@ func TestPrepareExtractsState(t *testing.T) {
 		ExecutionContext: new(action_kit_api.ExecutionContext{
 			ExperimentUri: new("<uri-to-experiment>"),
 			ExecutionUri:  new("<uri-to-execution>"),
+			ExperimentKey: new("ADM-1"),
+			ExecutionId:   new(42),
 		}),
 	})
 	action := k6LoadTestCloudAction{}
@@ -40,7 +53,37 @@ func TestPrepareExtractsState(t *testing.T) {
 	// Then
 	require.Nil(t, result)
 	require.Nil(t, err)
-	require.Equal(t, state.Command, []string([]string{"k6", "cloud", "run", "test.js"}))
+	require.Equal(t, state.Command, []string([]string{"k6", "cloud", "run", "test.js",
+		"--tag", "steadybit_execution_id=42", "--tag", "steadybit_experiment_key=ADM-1",
+		"--env", "STEADYBIT_EXECUTION_ID=42", "--env", "STEADYBIT_EXECUTION_URI=<uri-to-execution>",
+		"--env", "STEADYBIT_EXPERIMENT_KEY=ADM-1", "--env", "STEADYBIT_EXPERIMENT_URI=<uri-to-experiment>"}))
+	require.Equal(t, "default", state.CloudCredential)
+}
+
+func TestPrepareSelectsCloudCredential(t *testing.T) {
+	config.ParseConfiguration()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team-a"), []byte("token-a"), 0600))
+	config.Config.CloudCredentialsDir = dir
+	t.Cleanup(func() { config.Config.CloudCredentialsDir = "" })
+	action := k6LoadTestCloudAction{}
+
+	state := action.NewEmptyState()
+	result, err := action.Prepare(context.TODO(), &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{"file": "test.js", "credential": "team-a"},
+	}))
+	require.NoError(t, err)
+	require.Nil(t, result)
+	assert.Equal(t, "team-a", state.CloudCredential)
+	assert.NotContains(t, fmt.Sprintf("%+v", state), "token-a", "the token must not be kept in the state")
+
+	state = action.NewEmptyState()
+	result, err = action.Prepare(context.TODO(), &state, extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{"file": "test.js", "credential": "team-b"},
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "Invalid cloud credential: unknown cloud credential 'team-b'.", result.Error.Title)
 }
 
 func TestFailedCommandStart(t *testing.T) {
@@ -49,7 +92,7 @@ func TestFailedCommandStart(t *testing.T) {
 	state := action.NewEmptyState()
 	state.Command = []string([]string{"k6-not-available", "cloud", "test.js"})
 	state.ExecutionId = uuid.New()
-	config.Config.CloudApiToken = "test1234567890"
+	withCloudCredential(t)
 	// When
 	result, err := action.Start(context.TODO(), &state)
 
@@ -58,112 +101,263 @@ func TestFailedCommandStart(t *testing.T) {
 	require.Equal(t, "Failed to start command.: exec: \"k6-not-available\": executable file not found in $PATH", err.Error())
 }
 
-func Test_isCloudRunStillRunning(t *testing.T) {
+func Test_abortCloudRun(t *testing.T) {
 	config.ParseConfiguration()
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("GET", "https://api.k6.io/loadtests/v2/runs/2-running", httpmock.NewStringResponder(200, "{\"k6-run\": {\"run_status\": 2}}"))
-	httpmock.RegisterResponder("GET", "https://api.k6.io/loadtests/v2/runs/3-finished", httpmock.NewStringResponder(200, "{\"k6-run\": {\"run_status\": 3}}"))
-	httpmock.RegisterResponder("GET", "https://api.k6.io/loadtests/v2/runs/5-aborted", httpmock.NewStringResponder(200, "{\"k6-run\": {\"run_status\": 5}}"))
-	httpmock.RegisterResponder("GET", "https://api.k6.io/loadtests/v2/runs/98-error", httpmock.NewErrorResponder(fmt.Errorf("error")))
-	httpmock.RegisterResponder("GET", "https://api.k6.io/loadtests/v2/runs/99-invalid", httpmock.NewStringResponder(200, "invalid json"))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1-running", httpmock.NewStringResponder(200, `{"id": 1, "status": "running"}`))
+	httpmock.RegisterResponder("POST", "https://api.k6.io/cloud/v6/test_runs/1-running/abort", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/2-completed", httpmock.NewStringResponder(200, `{"id": 2, "status": "completed", "result": "passed"}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/3-abort-fails", httpmock.NewStringResponder(200, `{"id": 3, "status": "initializing"}`))
+	httpmock.RegisterResponder("POST", "https://api.k6.io/cloud/v6/test_runs/3-abort-fails/abort", httpmock.NewStringResponder(403, ""))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/4-finished-meanwhile", httpmock.NewStringResponder(200, `{"id": 4, "status": "running"}`))
+	httpmock.RegisterResponder("POST", "https://api.k6.io/cloud/v6/test_runs/4-finished-meanwhile/abort", httpmock.NewStringResponder(409, ""))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/99-invalid", httpmock.NewStringResponder(200, "invalid json"))
 	httpmock.RegisterNoResponder(httpmock.NewStringResponder(404, ""))
 
 	tests := []struct {
 		name       string
 		cloudRunId string
-		want       bool
+		wantAbort  bool
 		wantErr    assert.ErrorAssertionFunc
 	}{
 		{
 			name:       "Running",
-			cloudRunId: "2-running",
-			want:       true,
+			cloudRunId: "1-running",
+			wantAbort:  true,
 			wantErr:    assert.NoError,
 		},
 		{
-			name:       "Finished",
-			cloudRunId: "3-finished",
-			want:       false,
+			name:       "Completed",
+			cloudRunId: "2-completed",
+			wantAbort:  false,
 			wantErr:    assert.NoError,
 		},
 		{
-			name:       "Aborted",
-			cloudRunId: "5-aborted",
-			want:       false,
+			name:       "Abort Fails",
+			cloudRunId: "3-abort-fails",
+			wantAbort:  true,
+			wantErr:    assert.Error,
+		},
+		{
+			name:       "Finished Meanwhile",
+			cloudRunId: "4-finished-meanwhile",
+			wantAbort:  true,
 			wantErr:    assert.NoError,
 		},
 		{
 			name:       "Not Found",
 			cloudRunId: "404-not-found",
-			want:       false,
-			wantErr:    assert.Error,
-		},
-		{
-			name:       "Error",
-			cloudRunId: "98-error",
-			want:       false,
 			wantErr:    assert.Error,
 		},
 		{
 			name:       "Invalid",
 			cloudRunId: "99-invalid",
-			want:       false,
 			wantErr:    assert.Error,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			httpmock.ZeroCallCounters()
 
-			got, err := isCloudRunStillRunning(tt.cloudRunId)
-			if !tt.wantErr(t, err, fmt.Sprintf("isCloudRunStillRunning(= %v)", tt.cloudRunId)) {
-				return
-			}
-			if got != tt.want {
-				t.Errorf("isCloudRunStillRunning() got = %v, want %v", got, tt.want)
-			}
+			err := abortCloudRun(context.TODO(), newCloudClient(config.CloudCredential{Token: "test-token"}), tt.cloudRunId)
+
+			tt.wantErr(t, err, fmt.Sprintf("abortCloudRun(%v)", tt.cloudRunId))
+			aborts := httpmock.GetCallCountInfo()[fmt.Sprintf("POST https://api.k6.io/cloud/v6/test_runs/%s/abort", tt.cloudRunId)]
+			assert.Equal(t, tt.wantAbort, aborts == 1)
 		})
 	}
 }
 
-func Test_stopCloudRun(t *testing.T) {
+func TestStopReportsFailedCloudAbortAsMessage(t *testing.T) {
 	config.ParseConfiguration()
+	withCloudCredential(t)
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
-	httpmock.RegisterResponder("POST", "https://api.k6.io/loadtests/v2/runs/ok/stop", httpmock.NewStringResponder(200, "{\"k6-run\": {\"run_status\": 2}}"))
-	httpmock.RegisterResponder("POST", "https://api.k6.io/loadtests/v2/runs/failure/stop", httpmock.NewStringResponder(500, ""))
-	httpmock.RegisterResponder("POST", "https://api.k6.io/loadtests/v2/runs/error/stop", httpmock.NewErrorResponder(fmt.Errorf("error")))
-	httpmock.RegisterNoResponder(httpmock.NewStringResponder(404, ""))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234", httpmock.NewStringResponder(401, `{"error": {"message": "invalid token"}}`))
+	httpmock.RegisterNoResponder(httpmock.NewStringResponder(401, ""))
+	state := startFakeK6(t, `while true; do sleep 0.1; done`, 0)
+	state.CloudRunId = "1234"
+
+	result, err := (&k6LoadTestCloudAction{}).Stop(context.TODO(), state)
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	messages := *result.Messages
+	assert.Equal(t, action_kit_api.Error, *messages[0].Level)
+	assert.Equal(t, `Failed to abort the K6 cloud test run 1234: failed to get k6 cloud test run: GET https://api.k6.io/cloud/v6/test_runs/1234 responded with 401: {"error": {"message": "invalid token"}}`, messages[0].Message)
+	assert.NotNil(t, result.Artifacts, "k6 must be stopped nevertheless")
+}
+
+func TestPrepareRejectsContradictingLoadShape(t *testing.T) {
+	// Given
+	withCloudCredential(t)
+	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{
+			"file":         "test.js",
+			"testDuration": 1000 * 60,
+			"stages":       []map[string]string{{"key": "30s", "value": "10"}},
+		},
+	})
+	action := k6LoadTestCloudAction{}
+	state := action.NewEmptyState()
+
+	// When
+	result, err := action.Prepare(context.TODO(), &state, request)
+
+	// Then
+	require.Nil(t, err)
+	require.NotNil(t, result.Error)
+	require.Equal(t, "Invalid load shape: stages cannot be combined with a duration or iterations.", result.Error.Title)
+}
+
+func Test_pollCloudRun(t *testing.T) {
+	config.ParseConfiguration()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1-initializing", httpmock.NewStringResponder(200, `{"id": 1, "status": "initializing"}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/2-running", httpmock.NewStringResponder(200, `{"id": 2, "status": "running"}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(2-running)/query_aggregate_k6(metric='vus',query='max')", httpmock.NewStringResponder(200, `{"status": "success", "data": {"result": [{"values": [[1772619072, 25]]}]}}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/3-passed", httpmock.NewStringResponder(200, `{"id": 3, "status": "completed", "result": "passed"}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/4-failed", httpmock.NewStringResponder(200, `{"id": 4, "status": "completed", "result": "failed"}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/5-aborted", httpmock.NewStringResponder(200, `{"id": 5, "status": "aborted", "status_details": {"type": "aborted_threshold", "message": "http_req_failed rate<0.01 crossed"}}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/6-unauthorized", httpmock.NewStringResponder(401, ""))
 
 	tests := []struct {
-		name       string
-		cloudRunId string
-		wantErr    assert.ErrorAssertionFunc
+		name          string
+		cloudRunId    string
+		wantCompleted bool
+		wantStatus    *action_kit_api.ActionKitErrorStatus
+		wantMessages  []string
 	}{
 		{
-			name:       "OK",
-			cloudRunId: "ok",
-			wantErr:    assert.NoError,
+			name:         "Initializing",
+			cloudRunId:   "1-initializing",
+			wantMessages: []string{"K6 cloud test run 1-initializing is initializing."},
 		},
 		{
-			name:       "Not Found",
-			cloudRunId: "not-found",
-			wantErr:    assert.NoError,
+			name:         "Running",
+			cloudRunId:   "2-running",
+			wantMessages: []string{"K6 cloud test run 2-running is running.", "K6 cloud test run 2-running has reached 25 VUs."},
 		},
 		{
-			name:       "ServerError",
-			cloudRunId: "failure",
-			wantErr:    assert.NoError,
+			name:          "Passed",
+			cloudRunId:    "3-passed",
+			wantCompleted: true,
+			wantMessages:  []string{"K6 cloud test run 3-passed is completed.", "K6 cloud test run 3-passed has finished with result passed."},
 		},
 		{
-			name:       "error",
-			cloudRunId: "error",
-			wantErr:    assert.Error,
+			name:          "Failed",
+			cloudRunId:    "4-failed",
+			wantCompleted: true,
+			wantStatus:    new(action_kit_api.Failed),
+			wantMessages:  []string{"K6 cloud test run 4-failed is completed.", "K6 cloud test run 4-failed has finished with result failed."},
+		},
+		{
+			name:          "Aborted by threshold",
+			cloudRunId:    "5-aborted",
+			wantCompleted: true,
+			wantStatus:    new(action_kit_api.Failed),
+			wantMessages:  []string{"K6 cloud test run 5-aborted is aborted. http_req_failed rate<0.01 crossed", "K6 cloud test run 5-aborted has finished with result none."},
+		},
+		{
+			name:          "API failure keeps the local result",
+			cloudRunId:    "6-unauthorized",
+			wantCompleted: true,
+			wantStatus:    new(action_kit_api.Errored),
+			wantMessages:  []string{"Failed to get the status of the K6 cloud test run 6-unauthorized: GET https://api.k6.io/cloud/v6/test_runs/6-unauthorized responded with 401"},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.wantErr(t, stopCloudRun(tt.cloudRunId), fmt.Sprintf("stopCloudRun(%v)", tt.cloudRunId))
+			state := &K6LoadTestRunState{CloudRunId: tt.cloudRunId}
+			// the local k6 process has exited already
+			result := &action_kit_api.StatusResult{
+				Completed: true,
+				Error:     &action_kit_api.ActionKitError{Status: new(action_kit_api.Errored), Title: "K6 was aborted externally."},
+				Messages:  &[]action_kit_api.Message{},
+			}
+
+			pollCloudRun(context.TODO(), newCloudClient(config.CloudCredential{Token: "test-token"}), state, result)
+
+			assert.Equal(t, tt.wantCompleted, result.Completed)
+			if tt.wantStatus == nil {
+				assert.Nil(t, result.Error)
+			} else {
+				require.NotNil(t, result.Error)
+				assert.Equal(t, *tt.wantStatus, *result.Error.Status)
+			}
+			var messages []string
+			for _, m := range *result.Messages {
+				messages = append(messages, m.Message)
+			}
+			assert.Equal(t, tt.wantMessages, messages)
 		})
 	}
 }
+
+func TestCloudStatusDoesNotRetryPolling(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234", httpmock.NewStringResponder(503, ""))
+	state := &K6LoadTestRunState{CloudRunId: "1234"}
+
+	begin := time.Now()
+	result, err := (&k6LoadTestCloudAction{}).Status(context.TODO(), state)
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(begin), cloudPollTimeout)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	assert.Contains(t, (*result.Messages)[0].Message, "responded with 503")
+}
+
+func Test_pollCloudRun_reports_status_changes_once(t *testing.T) {
+	config.ParseConfiguration()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234", httpmock.NewStringResponder(200, `{"id": 1234, "status": "queued"}`))
+	state := &K6LoadTestRunState{CloudRunId: "1234"}
+
+	first := &action_kit_api.StatusResult{Messages: &[]action_kit_api.Message{}}
+	pollCloudRun(context.TODO(), newCloudClient(config.CloudCredential{Token: "test-token"}), state, first)
+	second := &action_kit_api.StatusResult{Messages: &[]action_kit_api.Message{}}
+	pollCloudRun(context.TODO(), newCloudClient(config.CloudCredential{Token: "test-token"}), state, second)
+
+	assert.Len(t, *first.Messages, 1)
+	assert.Empty(t, *second.Messages)
+	assert.Equal(t, "queued", state.CloudRunStatus)
+}
+
+func TestStopAttachesCloudResults(t *testing.T) {
+	config.ParseConfiguration()
+	withCloudCredential(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234", httpmock.NewStringResponder(200, `{"id": 1234, "status": "completed", "result": "failed"}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(1234)/thresholds",
+		httpmock.NewStringResponder(200, `{"value": [{"name": "http_req_duration: p(95)<500", "tainted": true, "calculated_value": 812.5}, {"name": "http_req_failed: rate<0.01", "tainted": false, "calculated_value": 0}]}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(1234)/checks",
+		httpmock.NewStringResponder(200, `{"value": [{"name": "status is 200", "metric_summary": {"success_count": 95, "fail_count": 5, "success_rate": 0.95}}]}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(1234)/http_urls", httpmock.NewStringResponder(403, ""))
+	state := startFakeK6(t, `echo "     output: cloud (https://mystack.grafana.net/a/k6-app/runs/1234)"; while true; do sleep 0.1; done`, 0)
+	_, err := status(state)
+	require.NoError(t, err)
+	require.Equal(t, "1234", state.CloudRunId)
+
+	result, err := (&k6LoadTestCloudAction{}).Stop(context.TODO(), state)
+
+	require.NoError(t, err)
+	text := messagesText(result)
+	assert.Contains(t, text, "K6 cloud test run: https://mystack.grafana.net/a/k6-app/runs/1234\n")
+	assert.Contains(t, text, "Threshold http_req_duration: p(95)<500 failed: 812.50\n")
+	assert.Contains(t, text, "Threshold http_req_failed: rate<0.01 passed: 0.00\n")
+	assert.Contains(t, text, "Check 'status is 200': 95.00% succeeded (95 passed, 5 failed)\n")
+	assert.Contains(t, text, "Failed to get the HTTP metrics of the K6 cloud test run 1234: GET https://api.k6.io/cloud/v5/test_runs(1234)/http_urls responded with 403\n")
+	labels := make([]string, 0)
+	for _, artifact := range *result.Artifacts {
+		labels = append(labels, artifact.Label)
+	}
+	assert.Contains(t, labels, "$(experimentKey)_$(executionId)_k6_cloud_results.json")
+}
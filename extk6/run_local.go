@@ -15,7 +15,10 @@ import (
 	"github.com/steadybit/extension-kit/extutil"
 )
 
-type K6LoadTestRunAction struct{}
+type K6LoadTestRunAction struct {
+	// fixedDuration makes the load test last as long as the experiment step instead of the script's own duration.
+	fixedDuration bool
+}
 
 // Make sure action implements all required interfaces
 var (
@@ -28,6 +31,10 @@ func NewK6LoadTestRunAction() action_kit_sdk.Action[K6LoadTestRunState] {
 	return &K6LoadTestRunAction{}
 }
 
+func NewK6LoadTestFixedDurationAction() action_kit_sdk.Action[K6LoadTestRunState] {
+	return &K6LoadTestRunAction{fixedDuration: true}
+}
+
 func (l *K6LoadTestRunAction) NewEmptyState() K6LoadTestRunState {
 	return K6LoadTestRunState{}
 }
@@ -37,14 +44,42 @@ func (l *K6LoadTestRunAction) Describe() action_kit_api.ActionDescription {
 		Content: "Please note that load tests are executed by the k6 extension participating in the experiment, consuming resources of the system that it is installed in.",
 		Type:    action_kit_api.HintWarning,
 	}
-	description := *getActionDescription(fmt.Sprintf("%s.run", actionIdPrefix), "K6", "Execute a K6 load test.", &hint)
+	var description action_kit_api.ActionDescription
+	if !l.fixedDuration {
+		description = *getActionDescription(fmt.Sprintf("%s.run", actionIdPrefix), "K6", "Execute a K6 load test.", &hint)
+	} else {
+		description = *getActionDescription(fmt.Sprintf("%s.run-fixed-duration", actionIdPrefix), "K6 (fixed duration)", "Execute a K6 load test for the duration of the experiment step.", &hint)
+		description.TimeControl = action_kit_api.TimeControlExternal
+		// the step's duration determines the load shape's duration
+		description.Parameters = append([]action_kit_api.ActionParameter{
+			{
+				Name:         "duration",
+				Label:        "Duration",
+				Description:  new("Duration of the load test, overrides the script's options. The load test is stopped gracefully at the end of the step."),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("60s"),
+				Required:     new(true),
+				Order:        new(0),
+			},
+		}, filter(description.Parameters, func(p action_kit_api.ActionParameter) bool {
+			return p.Name != "testDuration" && p.Name != "iterations" && p.Name != "stages"
+		})...)
+	}
+	description.Parameters = append(description.Parameters, outputsParameter(config.Config.Outputs)...)
+	description.Widgets = new(metricWidgets())
 
+	addLocationSelection(&description)
+	return description
+}
+
+// addLocationSelection lets users choose the extension instance running the load test, if enabled.
+func addLocationSelection(description *action_kit_api.ActionDescription) {
 	if config.Config.EnableLocationSelection {
 		description.Parameters = append(description.Parameters, action_kit_api.ActionParameter{
 			Name:  "-",
 			Label: "Filter K6 Locations",
 			Type:  action_kit_api.ActionParameterTypeTargetSelection,
-			Order: new(3),
+			Order: new(11),
 		})
 		description.TargetSelection = new(action_kit_api.TargetSelection{
 			TargetType: targetType,
@@ -55,32 +90,116 @@ func (l *K6LoadTestRunAction) Describe() action_kit_api.ActionDescription {
 			MissingQuerySelection: extutil.Ptr(action_kit_api.MissingQuerySelectionIncludeAll),
 		})
 	}
-	return description
 }
 
 func (l *K6LoadTestRunAction) Prepare(_ context.Context, state *K6LoadTestRunState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
-	var config K6LoadTestRunConfig
-	if err := extconversion.Convert(request.Config, &config); err != nil {
+	if l.fixedDuration {
+		// passed to k6 like the test duration of the regular action, overriding the script's options
+		request.Config["testDuration"] = request.Config["duration"]
+	}
+
+	return prepareLocal(state, request)
+}
+
+// prepareLocal prepares a load test run by the extension itself, additionally
+// writing the results to the given outputs and the selected configured ones.
+func prepareLocal(state *K6LoadTestRunState, request action_kit_api.PrepareActionRequestBody, outputs ...string) (*action_kit_api.PrepareResult, error) {
+	var runConfig K6LoadTestRunConfig
+	if err := extconversion.Convert(request.Config, &runConfig); err != nil {
 		return nil, extension_kit.ToError("Failed to unmarshal the config.", err)
 	}
+	selected, err := selectedOutputs(runConfig, config.Config.Outputs)
+	if err != nil {
+		return &action_kit_api.PrepareResult{
+			Error: &action_kit_api.ActionKitError{
+				Status: extutil.Ptr(action_kit_api.Errored),
+				Title:  fmt.Sprintf("Invalid outputs: %s.", err),
+			},
+		}, nil
+	}
+	state.Outputs = selected
+	script, err := prepareScript(state, request, runConfig)
+	if err != nil {
+		return nil, err
+	}
+	state.Vus = declaredVus(runConfig)
+	if limit := config.Config.MaxTotalVus; limit > 0 {
+		state.Vus = requiredVus(runConfig, state.WorkingDir, script)
+		if state.Vus > limit {
+			return &action_kit_api.PrepareResult{
+				Error: &action_kit_api.ActionKitError{
+					Status: extutil.Ptr(action_kit_api.Errored),
+					Title:  fmt.Sprintf("The load test with %d VUs exceeds the limit of %d VUs of this location.", state.Vus, limit),
+				},
+			}, nil
+		}
+	}
+
 	filename := fmt.Sprintf("/tmp/steadybit/%v/metrics.json", request.ExecutionId) //Folder is managed by action_kit_sdk's file download handling
 	command := []string{
 		"k6",
 		"run",
-		config.File,
+		script,
 		"--no-usage-report",
 		"--out",
 		fmt.Sprintf("json=%s", filename),
+		"--summary-export",
+		summaryExportFilename(request.ExecutionId),
+	}
+	for _, output := range outputs {
+		command = append(command, "--out", output)
 	}
+	command = append(command, outputArgs(selected, config.Config.Outputs)...)
 	return prepare(state, request, command)
 }
 
 func (l *K6LoadTestRunAction) Start(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StartResult, error) {
-	return start(state, "")
+	return startLocal(state, nil)
+}
+
+// startLocal starts a load test run by the extension itself once it is admitted.
+func startLocal(state *K6LoadTestRunState, env []string) (*action_kit_api.StartResult, error) {
+	return startAdmitted(state, localEnv(state, env))
+}
+
+// startServingApi starts k6 with its REST API on a loopback port, which is only
+// picked now, as another process could take it while the run is prepared or queued.
+func startServingApi(state *K6LoadTestRunState, env []string) (*action_kit_api.StartResult, error) {
+	apiAddress, err := freeApiAddress()
+	if err != nil {
+		admissions.release(state.ExecutionId.String())
+		return nil, extension_kit.ToError("Failed to find a free port for the k6 REST API.", err)
+	}
+	state.ApiAddress = apiAddress
+	state.Command = append(state.Command, "--address", apiAddress)
+	return start(state, env)
+}
+
+// localEnv adds the settings of the selected outputs to the environment variables.
+func localEnv(state *K6LoadTestRunState, env []string) []string {
+	return append(outputsEnv(state.Outputs, config.Config.Outputs), env...)
 }
 
 func (l *K6LoadTestRunAction) Status(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StatusResult, error) {
-	return status(state)
+	if state.QueuedAt != nil {
+		return statusQueued(state, localEnv(state, nil))
+	}
+	return statusLocal(state)
+}
+
+// statusLocal reports the status of a load test run by the extension itself,
+// including the metrics of its REST API.
+func statusLocal(state *K6LoadTestRunState) (*action_kit_api.StatusResult, error) {
+	// poll the REST API first, it is gone as soon as k6 has finished
+	metrics := fetchMetrics(state.ApiAddress)
+	result, err := status(state)
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) > 0 {
+		result.Metrics = new(metrics)
+	}
+	return result, nil
 }
 
 func (l *K6LoadTestRunAction) Stop(_ context.Context, state *K6LoadTestRunState) (*action_kit_api.StopResult, error) {
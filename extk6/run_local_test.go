@@ -0,0 +1,85 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parameterNames(description action_kit_api.ActionDescription) []string {
+	names := make([]string, 0, len(description.Parameters))
+	for _, p := range description.Parameters {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestFixedDurationActionIsExternallyControlled(t *testing.T) {
+	regular := NewK6LoadTestRunAction().Describe()
+	fixed := NewK6LoadTestFixedDurationAction().Describe()
+
+	assert.Equal(t, action_kit_api.TimeControlInternal, regular.TimeControl)
+	assert.Equal(t, action_kit_api.TimeControlExternal, fixed.TimeControl)
+	assert.NotEqual(t, regular.Id, fixed.Id)
+	assert.Equal(t, "duration", fixed.Parameters[0].Name)
+	assert.NotContains(t, parameterNames(fixed), "testDuration")
+	assert.NotContains(t, parameterNames(fixed), "stages")
+	assert.Contains(t, parameterNames(fixed), "vus")
+}
+
+func TestFixedDurationPreparePassesDurationToK6(t *testing.T) {
+	// Given
+	request := extutil.JsonMangle(action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{
+			"duration": 1000 * 60,
+			"vus":      5,
+			"file":     "test.js",
+		},
+		ExecutionId: uuid.New(),
+	})
+	action := NewK6LoadTestFixedDurationAction()
+	state := action.NewEmptyState()
+
+	// When
+	result, err := action.Prepare(context.TODO(), &state, request)
+
+	// Then
+	require.Nil(t, result)
+	require.Nil(t, err)
+	assert.Subset(t, state.Command, []string{"k6", "run", "test.js", "--vus", "5", "--duration", "1m0s"})
+	assert.NotContains(t, state.Command, "--address", "the REST API port is picked on start")
+	assert.Empty(t, state.ApiAddress)
+}
+
+func TestStartPicksApiAddress(t *testing.T) {
+	state := &K6LoadTestRunState{
+		Command:     []string{"sh", "-c", `echo "api at $1"`},
+		ExecutionId: newExecution(t),
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(summaryExportFilename(state.ExecutionId)), 0755))
+
+	_, err := startLocal(state, nil)
+	require.NoError(t, err)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	result, err := status(state)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, state.ApiAddress)
+	var messages []string
+	for _, m := range *result.Messages {
+		messages = append(messages, m.Message)
+	}
+	assert.Contains(t, messages, "api at "+state.ApiAddress)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runningTests holds the k6 REST API address of each load test run by this
+// extension instance by its command state id, empty for cloud test runs.
+var runningTests = sync.Map{}
+
+// runsChanged is signalled when a load test has started or ended, to refresh
+// the attributes of the location.
+var runsChanged = make(chan struct{}, 1)
+
+type statusResponse struct {
+	Data struct {
+		Attributes struct {
+			Vus int `json:"vus"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func registerRunningTest(cmdStateId string, apiAddress string) {
+	runningTests.Store(cmdStateId, apiAddress)
+	notifyRunsChanged()
+}
+
+func unregisterRunningTest(cmdStateId string) {
+	runningTests.Delete(cmdStateId)
+	notifyRunsChanged()
+}
+
+func notifyRunsChanged() {
+	select {
+	case runsChanged <- struct{}{}:
+	default:
+		// a refresh is pending already
+	}
+}
+
+// runningTestsLoad returns the number of running load tests and the VUs they
+// currently run on this location.
+func runningTestsLoad() (tests int, vus int) {
+	runningTests.Range(func(_, value any) bool {
+		tests++
+		if address := value.(string); address != "" {
+			vus += fetchVus(address)
+		}
+		return true
+	})
+	return tests, vus
+}
+
+// fetchVus polls the current VUs from the k6 REST API at address, 0 if it is not
+// reachable.
+func fetchVus(address string) int {
+	res, err := metricsClient.Get(fmt.Sprintf("http://%s/v1/status", address))
+	if err != nil {
+		log.Debug().Err(err).Msgf("Failed to fetch k6 status from %s", address)
+		return 0
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode != http.StatusOK {
+		log.Debug().Msgf("Failed to fetch k6 status from %s: %s", address, res.Status)
+		return 0
+	}
+	var status statusResponse
+	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
+		log.Debug().Err(err).Msgf("Failed to decode k6 status from %s", address)
+		return 0
+	}
+	return status.Data.Attributes.Vus
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runningTestsLoad(t *testing.T) {
+	k6Api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/status" {
+			_, _ = w.Write([]byte(`{"data": {"type": "status", "id": "default", "attributes": {"status": 7, "paused": false, "vus": 25, "running": true}}}`))
+		}
+	}))
+	defer k6Api.Close()
+	testsBefore, vusBefore := runningTestsLoad()
+
+	registerRunningTest("local", strings.TrimPrefix(k6Api.URL, "http://"))
+	registerRunningTest("cloud", "")
+	tests, vus := runningTestsLoad()
+	unregisterRunningTest("local")
+	unregisterRunningTest("cloud")
+
+	assert.Equal(t, testsBefore+2, tests)
+	assert.Equal(t, vusBefore+25, vus)
+	testsAfter, _ := runningTestsLoad()
+	assert.Equal(t, testsBefore, testsAfter)
+}
+
+func Test_start_registers_running_test(t *testing.T) {
+	// drain pending notifications
+	select {
+	case <-runsChanged:
+	default:
+	}
+
+	state := startFakeK6(t, `sleep 0.5`, 0)
+
+	_, registered := runningTests.Load(state.CmdStateID)
+	assert.True(t, registered)
+	select {
+	case <-runsChanged:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the start must be notified")
+	}
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	_, registered = runningTests.Load(state.CmdStateID)
+	assert.False(t, registered)
+}
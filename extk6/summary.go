@@ -0,0 +1,364 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// summaryTags are the point tags the summary is broken down by.
+var summaryTags = []string{"scenario", "name", "status", "group"}
+
+// exactValues is how many values of a series are kept to calculate its
+// percentiles exactly, beyond that they are approximated by a sketch, so that
+// the memory used doesn't grow with the length of the load test.
+const exactValues = 1000
+
+// sketchAccuracy is the relative accuracy of the approximated percentiles.
+const sketchAccuracy = 0.01
+
+// maxSummaryMessages limits the number of messages created from a summary, the
+// summary.json artifact always contains all series.
+const maxSummaryMessages = 200
+
+type metricSummary struct {
+	Metric string            `json:"metric"`
+	Type   string            `json:"type"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	Count  int               `json:"count"`
+	Rate   float64           `json:"rate"`
+	Avg    float64           `json:"avg"`
+	Min    float64           `json:"min"`
+	Med    float64           `json:"med"`
+	P90    float64           `json:"p90"`
+	P95    float64           `json:"p95"`
+	P99    float64           `json:"p99"`
+	Max    float64           `json:"max"`
+}
+
+type jsonOutputLine struct {
+	Type   string `json:"type"`
+	Metric string `json:"metric"`
+	Data   struct {
+		Type  string            `json:"type"`
+		Time  time.Time         `json:"time"`
+		Value float64           `json:"value"`
+		Tags  map[string]string `json:"tags"`
+	} `json:"data"`
+}
+
+type series struct {
+	metric  string
+	tags    map[string]string
+	count   int
+	sum     float64
+	min     float64
+	max     float64
+	nonZero int
+	// values are kept until there are more than exactValues, then sketched
+	values []float64
+	sketch *sketch
+}
+
+// summarizeJsonOutput stream-parses the NDJSON file written by k6's `--out json=`
+// and aggregates the points per metric, once overall and once per combination of
+// the summaryTags.
+func summarizeJsonOutput(path string) ([]metricSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	metricTypes := make(map[string]string)
+	allSeries := make(map[string]*series)
+	var first, last time.Time
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line jsonOutputLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		switch line.Type {
+		case "Metric":
+			metricTypes[line.Metric] = line.Data.Type
+		case "Point":
+			if first.IsZero() || line.Data.Time.Before(first) {
+				first = line.Data.Time
+			}
+			if line.Data.Time.After(last) {
+				last = line.Data.Time
+			}
+			addPoint(allSeries, line.Metric, nil, line.Data.Value)
+			if tags := pickSummaryTags(line.Data.Tags); len(tags) > 0 {
+				addPoint(allSeries, line.Metric, tags, line.Data.Value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	duration := last.Sub(first).Seconds()
+	summaries := make([]metricSummary, 0, len(allSeries))
+	for _, s := range allSeries {
+		summaries = append(summaries, s.summarize(metricTypes[s.metric], duration))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Metric != summaries[j].Metric {
+			return summaries[i].Metric < summaries[j].Metric
+		}
+		return formatTags(summaries[i].Tags) < formatTags(summaries[j].Tags)
+	})
+	return summaries, nil
+}
+
+func pickSummaryTags(tags map[string]string) map[string]string {
+	picked := make(map[string]string)
+	for _, tag := range summaryTags {
+		if value := tags[tag]; value != "" {
+			picked[tag] = value
+		}
+	}
+	return picked
+}
+
+func addPoint(allSeries map[string]*series, metric string, tags map[string]string, value float64) {
+	key := metric + formatTags(tags)
+	s, ok := allSeries[key]
+	if !ok {
+		s = &series{metric: metric, tags: tags}
+		allSeries[key] = s
+	}
+	s.add(value)
+}
+
+func (s *series) add(value float64) {
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	s.count++
+	s.sum += value
+	if value != 0 {
+		s.nonZero++
+	}
+
+	if s.sketch != nil {
+		s.sketch.add(value)
+		return
+	}
+	s.values = append(s.values, value)
+	if len(s.values) > exactValues {
+		s.sketch = newSketch(sketchAccuracy)
+		for _, v := range s.values {
+			s.sketch.add(v)
+		}
+		s.values = nil
+	}
+}
+
+func (s *series) summarize(metricType string, duration float64) metricSummary {
+	percentileOf := func(p float64) float64 {
+		return min(max(s.sketch.quantile(p), s.min), s.max)
+	}
+	if s.sketch == nil {
+		sort.Float64s(s.values)
+		percentileOf = func(p float64) float64 { return percentile(s.values, p) }
+	}
+
+	summary := metricSummary{
+		Metric: s.metric,
+		Type:   metricType,
+		Tags:   s.tags,
+		Count:  s.count,
+		Avg:    s.sum / float64(s.count),
+		Min:    s.min,
+		Med:    percentileOf(0.5),
+		P90:    percentileOf(0.9),
+		P95:    percentileOf(0.95),
+		P99:    percentileOf(0.99),
+		Max:    s.max,
+	}
+
+	switch {
+	case metricType == "rate":
+		summary.Rate = float64(s.nonZero) / float64(s.count)
+	case duration <= 0:
+		summary.Rate = 0
+	case metricType == "counter":
+		summary.Rate = s.sum / duration
+	default:
+		summary.Rate = float64(s.count) / duration
+	}
+	return summary
+}
+
+// percentile interpolates linearly between the closest ranks of the sorted values,
+// the same way k6 calculates the percentiles of its trend metrics.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// sketch approximates the distribution of values by counting them in buckets
+// growing exponentially, so that the quantiles have a bounded relative error and
+// the number of buckets only grows with the logarithm of the range of values.
+type sketch struct {
+	gamma    float64
+	positive map[int]int
+	negative map[int]int
+	zeros    int
+	count    int
+}
+
+func newSketch(accuracy float64) *sketch {
+	return &sketch{
+		gamma:    (1 + accuracy) / (1 - accuracy),
+		positive: make(map[int]int),
+		negative: make(map[int]int),
+	}
+}
+
+func (k *sketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) / math.Log(k.gamma)))
+}
+
+// value is the representative value of the bucket, which is within the
+// accuracy of all values counted in it.
+func (k *sketch) value(index int) float64 {
+	return 2 * math.Pow(k.gamma, float64(index)) / (k.gamma + 1)
+}
+
+func (k *sketch) add(value float64) {
+	switch {
+	case value > 0:
+		k.positive[k.index(value)]++
+	case value < 0:
+		k.negative[k.index(-value)]++
+	default:
+		k.zeros++
+	}
+	k.count++
+}
+
+// quantile returns the value of the bucket containing the closest rank of p.
+func (k *sketch) quantile(p float64) float64 {
+	rank := int(math.Round(p * float64(k.count-1)))
+
+	negative := sortedKeys(k.negative)
+	for i := len(negative) - 1; i >= 0; i-- {
+		if rank < k.negative[negative[i]] {
+			return -k.value(negative[i])
+		}
+		rank -= k.negative[negative[i]]
+	}
+	if rank < k.zeros {
+		return 0
+	}
+	rank -= k.zeros
+	positive := sortedKeys(k.positive)
+	for _, index := range positive {
+		if rank < k.positive[index] {
+			return k.value(index)
+		}
+		rank -= k.positive[index]
+	}
+	if len(positive) > 0 {
+		return k.value(positive[len(positive)-1])
+	}
+	return 0
+}
+
+func sortedKeys(buckets map[int]int) []int {
+	keys := make([]int, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(tags))
+	for _, tag := range summaryTags {
+		if value, ok := tags[tag]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", tag, value))
+		}
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+func formatValue(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+func summaryToMessages(summaries []metricSummary) []action_kit_api.Message {
+	messages := make([]action_kit_api.Message, 0, min(len(summaries), maxSummaryMessages)+1)
+	for i, s := range summaries {
+		if i == maxSummaryMessages {
+			messages = append(messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("%d more metric series are contained in the summary artifact.", len(summaries)-maxSummaryMessages),
+			})
+			break
+		}
+
+		fields := action_kit_api.MessageFields{
+			"metric": s.Metric,
+			"type":   s.Type,
+			"count":  fmt.Sprintf("%d", s.Count),
+			"rate":   formatValue(s.Rate),
+			"avg":    formatValue(s.Avg),
+			"min":    formatValue(s.Min),
+			"med":    formatValue(s.Med),
+			"p90":    formatValue(s.P90),
+			"p95":    formatValue(s.P95),
+			"p99":    formatValue(s.P99),
+			"max":    formatValue(s.Max),
+		}
+		for tag, value := range s.Tags {
+			fields[tag] = value
+		}
+		messages = append(messages, action_kit_api.Message{
+			Level: extutil.Ptr(action_kit_api.Info),
+			Message: fmt.Sprintf("%s%s: count=%d rate=%s avg=%s min=%s med=%s p(90)=%s p(95)=%s p(99)=%s max=%s",
+				s.Metric, formatTags(s.Tags), s.Count, formatValue(s.Rate), formatValue(s.Avg), formatValue(s.Min),
+				formatValue(s.Med), formatValue(s.P90), formatValue(s.P95), formatValue(s.P99), formatValue(s.Max)),
+			Fields: &fields,
+		})
+	}
+	return messages
+}
+
+func writeSummary(path string, summaries []metricSummary) error {
+	content, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonOutput = `{"type":"Metric","data":{"name":"http_reqs","type":"counter","contains":"default","thresholds":[],"submetrics":null},"metric":"http_reqs"}
+{"type":"Point","data":{"time":"2026-01-01T10:00:00Z","value":1,"tags":{"name":"home","status":"200","scenario":"default","method":"GET"}},"metric":"http_reqs"}
+{"type":"Point","data":{"time":"2026-01-01T10:00:05Z","value":1,"tags":{"name":"home","status":"500","scenario":"default","method":"GET"}},"metric":"http_reqs"}
+{"type":"Metric","data":{"name":"http_req_duration","type":"trend","contains":"time","thresholds":[],"submetrics":null},"metric":"http_req_duration"}
+{"type":"Point","data":{"time":"2026-01-01T10:00:00Z","value":10,"tags":{"name":"home","status":"200","scenario":"default"}},"metric":"http_req_duration"}
+{"type":"Point","data":{"time":"2026-01-01T10:00:05Z","value":30,"tags":{"name":"home","status":"500","scenario":"default"}},"metric":"http_req_duration"}
+{"type":"Point","data":{"time":"2026-01-01T10:00:10Z","value":20,"tags":{"name":"home","status":"200","scenario":"default"}},"metric":"http_req_duration"}
+{"type":"Metric","data":{"name":"http_req_failed","type":"rate","contains":"default","thresholds":[],"submetrics":null},"metric":"http_req_failed"}
+{"type":"Point","data":{"time":"2026-01-01T10:00:00Z","value":0,"tags":{}},"metric":"http_req_failed"}
+{"type":"Point","data":{"time":"2026-01-01T10:00:05Z","value":1,"tags":{}},"metric":"http_req_failed"}
+not a json line
+`
+
+func Test_summarizeJsonOutput(t *testing.T) {
+	path := writeFile(t, "metrics.json", []byte(jsonOutput))
+
+	summaries, err := summarizeJsonOutput(path)
+
+	require.NoError(t, err)
+	byKey := make(map[string]metricSummary)
+	for _, s := range summaries {
+		byKey[s.Metric+formatTags(s.Tags)] = s
+	}
+	assert.Len(t, summaries, 7)
+
+	duration := byKey["http_req_duration"]
+	assert.Equal(t, "trend", duration.Type)
+	assert.Equal(t, 3, duration.Count)
+	assert.Equal(t, 20.0, duration.Avg)
+	assert.Equal(t, 10.0, duration.Min)
+	assert.Equal(t, 20.0, duration.Med)
+	assert.InDelta(t, 29.0, duration.P95, 0.001)
+	assert.Equal(t, 30.0, duration.Max)
+	assert.InDelta(t, 0.3, duration.Rate, 0.001)
+
+	ok := byKey["http_req_duration{scenario=default,name=home,status=200}"]
+	assert.Equal(t, 2, ok.Count)
+	assert.Equal(t, 15.0, ok.Avg)
+
+	reqs := byKey["http_reqs"]
+	assert.Equal(t, 2, reqs.Count)
+	assert.InDelta(t, 0.2, reqs.Rate, 0.001)
+
+	failed := byKey["http_req_failed"]
+	assert.Equal(t, 0.5, failed.Rate)
+}
+
+func Test_summarizeJsonOutput_approximates_percentiles_of_long_runs(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString(`{"type":"Metric","data":{"name":"http_req_duration","type":"trend"},"metric":"http_req_duration"}` + "\n")
+	for i := 1; i <= 100000; i++ {
+		fmt.Fprintf(&sb, `{"type":"Point","data":{"time":"2026-01-01T10:00:00Z","value":%d,"tags":{"status":"200"}},"metric":"http_req_duration"}`+"\n", i)
+	}
+	path := writeFile(t, "metrics.json", []byte(sb.String()))
+
+	summaries, err := summarizeJsonOutput(path)
+
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	duration := summaries[0]
+	assert.Equal(t, 100000, duration.Count)
+	assert.Equal(t, 50000.5, duration.Avg)
+	assert.Equal(t, 1.0, duration.Min)
+	assert.Equal(t, 100000.0, duration.Max)
+	assert.InEpsilon(t, 50000.5, duration.Med, sketchAccuracy)
+	assert.InEpsilon(t, 95000.05, duration.P95, sketchAccuracy)
+	assert.InEpsilon(t, 99000.01, duration.P99, sketchAccuracy)
+}
+
+func Test_series_keeps_bounded_aggregates(t *testing.T) {
+	s := &series{}
+	for i := 0; i < 10*exactValues; i++ {
+		s.add(float64(i % 500))
+	}
+
+	assert.Nil(t, s.values)
+	assert.Less(t, len(s.sketch.positive), 500)
+	assert.Equal(t, 10*exactValues/500, s.sketch.zeros)
+}
+
+func Test_sketch_quantile_with_negative_values(t *testing.T) {
+	k := newSketch(sketchAccuracy)
+	for _, v := range []float64{-100, -10, 0, 10, 100} {
+		k.add(v)
+	}
+
+	assert.InEpsilon(t, -100, k.quantile(0), sketchAccuracy)
+	assert.InEpsilon(t, -10, k.quantile(0.25), sketchAccuracy)
+	assert.Zero(t, k.quantile(0.5))
+	assert.InEpsilon(t, 100, k.quantile(1), sketchAccuracy)
+}
+
+func Test_summarizeJsonOutput_missing_file(t *testing.T) {
+	_, err := summarizeJsonOutput(filepath.Join(t.TempDir(), "absent.json"))
+
+	assert.Error(t, err)
+}
+
+func Test_summaryToMessages(t *testing.T) {
+	summaries := make([]metricSummary, maxSummaryMessages+5)
+	for i := range summaries {
+		summaries[i] = metricSummary{Metric: "http_req_duration", Type: "trend", Tags: map[string]string{"status": "200"}, Count: 1, P95: 812}
+	}
+
+	messages := summaryToMessages(summaries)
+
+	require.Len(t, messages, maxSummaryMessages+1)
+	assert.True(t, strings.HasPrefix(messages[0].Message, "http_req_duration{status=200}: count=1"))
+	assert.Contains(t, messages[0].Message, "p(95)=812.00")
+	assert.Equal(t, "812.00", (*messages[0].Fields)["p95"])
+	assert.Equal(t, "200", (*messages[0].Fields)["status"])
+	assert.Equal(t, "5 more metric series are contained in the summary artifact.", messages[maxSummaryMessages].Message)
+}
+
+func Test_writeSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+
+	require.NoError(t, writeSummary(path, []metricSummary{{Metric: "vus", Type: "gauge", Count: 1, Max: 5}}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var written []metricSummary
+	require.NoError(t, json.Unmarshal(content, &written))
+	assert.Equal(t, "vus", written[0].Metric)
+	assert.Equal(t, 5.0, written[0].Max)
+}
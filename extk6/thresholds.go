@@ -0,0 +1,187 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// timeMetrics are k6's built-in metrics measured in milliseconds.
+var timeMetrics = []string{
+	"http_req_duration",
+	"http_req_blocked",
+	"http_req_connecting",
+	"http_req_tls_handshaking",
+	"http_req_sending",
+	"http_req_waiting",
+	"http_req_receiving",
+	"iteration_duration",
+	"group_duration",
+	"ws_connecting",
+	"ws_session_duration",
+	"ws_ping",
+	"grpc_req_duration",
+}
+
+type thresholdFailure struct {
+	Metric     string
+	Expression string
+	Observed   *float64
+}
+
+func summaryExportFilename(executionId uuid.UUID) string {
+	return fmt.Sprintf("/tmp/steadybit/%v/k6_summary_export.json", executionId) //Folder is managed by action_kit_sdk's file download handling
+}
+
+// readFailedThresholds reads the failed thresholds from a file written by k6's
+// `--summary-export`. The data passed to `handleSummary` is supported as well, it
+// nests the values and reports thresholds as `{"ok": false}` instead of `true`.
+func readFailedThresholds(path string) ([]thresholdFailure, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var export struct {
+		Metrics map[string]map[string]json.RawMessage `json:"metrics"`
+	}
+	if err := json.Unmarshal(content, &export); err != nil {
+		return nil, err
+	}
+
+	failures := make([]thresholdFailure, 0)
+	for metric, fields := range export.Metrics {
+		var thresholds map[string]json.RawMessage
+		if err := json.Unmarshal(fields["thresholds"], &thresholds); err != nil {
+			continue
+		}
+		values := fields
+		if nested, ok := fields["values"]; ok {
+			_ = json.Unmarshal(nested, &values)
+		}
+		for expression, result := range thresholds {
+			if !thresholdFailed(result) {
+				continue
+			}
+			failures = append(failures, thresholdFailure{
+				Metric:     metric,
+				Expression: expression,
+				Observed:   observedValue(values, thresholdAggregation(expression)),
+			})
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Metric != failures[j].Metric {
+			return failures[i].Metric < failures[j].Metric
+		}
+		return failures[i].Expression < failures[j].Expression
+	})
+	return failures, nil
+}
+
+func thresholdFailed(result json.RawMessage) bool {
+	var failed bool
+	if err := json.Unmarshal(result, &failed); err == nil {
+		return failed
+	}
+	var summary struct {
+		Ok *bool `json:"ok"`
+	}
+	if err := json.Unmarshal(result, &summary); err == nil && summary.Ok != nil {
+		return !*summary.Ok
+	}
+	return false
+}
+
+// thresholdAggregation returns the aggregation a threshold expression like
+// `p(95)<500` checks.
+func thresholdAggregation(expression string) string {
+	if i := strings.IndexAny(expression, "<>=!"); i >= 0 {
+		return strings.TrimSpace(expression[:i])
+	}
+	return strings.TrimSpace(expression)
+}
+
+func observedValue(values map[string]json.RawMessage, aggregation string) *float64 {
+	keys := []string{aggregation}
+	if aggregation == "rate" {
+		// the summary export names the rate of rate metrics "value"
+		keys = append(keys, "value")
+	}
+	for _, key := range keys {
+		var value float64
+		if raw, ok := values[key]; ok && json.Unmarshal(raw, &value) == nil {
+			return &value
+		}
+	}
+	return nil
+}
+
+func (f thresholdFailure) String() string {
+	if f.Observed == nil {
+		return fmt.Sprintf("%s %s failed", f.Metric, f.Expression)
+	}
+	observed := strconv.FormatFloat(math.Round(*f.Observed*100)/100, 'f', -1, 64)
+	baseMetric, _, _ := strings.Cut(f.Metric, "{")
+	if slices.Contains(timeMetrics, baseMetric) {
+		observed += "ms"
+	}
+	return fmt.Sprintf("%s %s failed: %s", f.Metric, f.Expression, observed)
+}
+
+func thresholdFailureDetail(failures []thresholdFailure) *string {
+	if len(failures) == 0 {
+		return nil
+	}
+	lines := make([]string, len(failures))
+	for i, failure := range failures {
+		lines[i] = failure.String()
+	}
+	return extutil.Ptr(strings.Join(lines, "\n"))
+}
+
+func thresholdFailureMessages(failures []thresholdFailure) []action_kit_api.Message {
+	messages := make([]action_kit_api.Message, 0, len(failures))
+	for _, failure := range failures {
+		messages = append(messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Error),
+			Message: failure.String(),
+			Fields: extutil.Ptr(action_kit_api.MessageFields{
+				"metric":    failure.Metric,
+				"threshold": failure.Expression,
+			}),
+		})
+	}
+	return messages
+}
+
+// reportFailedThresholds returns the failed thresholds of the run, but only on the
+// first call, so that status and stop don't report them twice.
+func reportFailedThresholds(state *K6LoadTestRunState) []thresholdFailure {
+	if state.ThresholdsReported {
+		return nil
+	}
+	failures, err := readFailedThresholds(summaryExportFilename(state.ExecutionId))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Msg("Failed to read the k6 summary export.")
+		}
+		return nil
+	}
+	state.ThresholdsReported = true
+	return failures
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package extk6
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const summaryExport = `{
+  "metrics": {
+    "http_req_duration": {"avg": 420.5, "med": 380, "p(90)": 700.1, "p(95)": 812, "thresholds": {"p(95)<500": true, "avg<1000": false}},
+    "http_req_duration{status:200}": {"avg": 120.2, "p(95)": 180.456, "thresholds": {"p(95)<150": true}},
+    "http_req_failed": {"passes": 5, "fails": 95, "value": 0.05, "thresholds": {"rate<0.01": true}},
+    "checks": {"passes": 100, "fails": 0, "value": 1},
+    "my_counter": {"count": 3, "rate": 0.1, "thresholds": {"count>10": true}}
+  }
+}`
+
+const handleSummaryData = `{
+  "metrics": {
+    "http_req_duration": {"type": "trend", "contains": "time", "values": {"avg": 420.5, "p(99)": 1500}, "thresholds": {"p(99)<1000": {"ok": false}, "avg<1000": {"ok": true}}}
+  }
+}`
+
+func Test_readFailedThresholds(t *testing.T) {
+	failures, err := readFailedThresholds(writeFile(t, "k6_summary_export.json", []byte(summaryExport)))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"http_req_duration p(95)<500 failed: 812ms",
+		"http_req_duration{status:200} p(95)<150 failed: 180.46ms",
+		"http_req_failed rate<0.01 failed: 0.05",
+		"my_counter count>10 failed: 3",
+	}, thresholdFailureStrings(failures))
+}
+
+func Test_readFailedThresholds_from_handleSummary_data(t *testing.T) {
+	failures, err := readFailedThresholds(writeFile(t, "summary.json", []byte(handleSummaryData)))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"http_req_duration p(99)<1000 failed: 1500ms"}, thresholdFailureStrings(failures))
+}
+
+func Test_thresholdFailure_without_observed_value(t *testing.T) {
+	failure := thresholdFailure{Metric: "http_req_duration", Expression: "p(99.9)<2000"}
+	assert.Equal(t, "http_req_duration p(99.9)<2000 failed", failure.String())
+}
+
+func Test_status_reports_failed_thresholds(t *testing.T) {
+	state := startFakeK6(t, `exit 99`, 0)
+	require.True(t, awaitExit(state.CmdStateID, 5*time.Second))
+	require.NoError(t, os.WriteFile(summaryExportFilename(state.ExecutionId), []byte(summaryExport), 0644))
+
+	result, err := status(state)
+
+	require.NoError(t, err)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, action_kit_api.Failed, *result.Error.Status)
+	assert.Equal(t, "http_req_duration p(95)<500 failed: 812ms\nhttp_req_duration{status:200} p(95)<150 failed: 180.46ms\nhttp_req_failed rate<0.01 failed: 0.05\nmy_counter count>10 failed: 3", *result.Error.Detail)
+	assert.Contains(t, *result.Messages, action_kit_api.Message{
+		Level:   new(action_kit_api.Error),
+		Message: "http_req_duration p(95)<500 failed: 812ms",
+		Fields:  new(action_kit_api.MessageFields{"metric": "http_req_duration", "threshold": "p(95)<500"}),
+	})
+
+	stopResult, err := stop(state)
+	require.NoError(t, err)
+	assert.NotContains(t, messagesText(stopResult), "p(95)<500 failed", "thresholds must only be reported once")
+	assert.Contains(t, messagesText(stopResult), "K6 run failed with exit code 99")
+}
+
+func thresholdFailureStrings(failures []thresholdFailure) []string {
+	result := make([]string, len(failures))
+	for i, failure := range failures {
+		result[i] = failure.String()
+	}
+	return result
+}
@@ -0,0 +1,348 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+// Package k6cloud is a client for the Grafana Cloud k6 REST API.
+// See https://grafana.com/docs/grafana-cloud/testing/k6/reference/cloud-rest-api/
+package k6cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Test run states reported by the v6 API.
+const (
+	StatusCreated           = "created"
+	StatusQueued            = "queued"
+	StatusInitializing      = "initializing"
+	StatusRunning           = "running"
+	StatusProcessingMetrics = "processing_metrics"
+	StatusCompleted         = "completed"
+	StatusAborted           = "aborted"
+)
+
+// Test run results reported by the v6 API once the run has finished.
+const (
+	ResultPassed = "passed"
+	ResultFailed = "failed"
+	ResultError  = "error"
+)
+
+// runUrlPattern matches the links to a test run printed by k6, either to the
+// legacy k6 Cloud app or to the k6 app of a Grafana Cloud stack.
+var runUrlPattern = regexp.MustCompile(`https://(?:app\.k6\.io|[\w.-]+\.grafana\.net/a/k6-app)/runs/(\d+)`)
+
+const (
+	// requestTimeout limits a single request, including reading the response body.
+	requestTimeout = 10 * time.Second
+	// maxRetries is how often a request is retried after a server error, a rate
+	// limit or a failed connection.
+	maxRetries = 3
+	// initialBackoff is the delay before the first retry, it doubles with every retry.
+	initialBackoff = 500 * time.Millisecond
+	// maxBackoff limits the delay between retries, also when requested by the server.
+	maxBackoff = 10 * time.Second
+)
+
+type Client struct {
+	baseUrl        string
+	token          string
+	stackId        string
+	httpClient     *http.Client
+	maxRetries     int
+	initialBackoff time.Duration
+}
+
+type TestRun struct {
+	Id            int64         `json:"id"`
+	TestId        int64         `json:"test_id"`
+	ProjectId     int64         `json:"project_id"`
+	Status        string        `json:"status"`
+	StatusDetails StatusDetails `json:"status_details"`
+	Result        *string       `json:"result"`
+	Created       *time.Time    `json:"created"`
+	Ended         *time.Time    `json:"ended"`
+}
+
+type StatusDetails struct {
+	Type    string     `json:"type"`
+	Entered *time.Time `json:"entered"`
+	Message string     `json:"message,omitempty"`
+}
+
+// Threshold is the result of a threshold of a test run.
+type Threshold struct {
+	Name            string   `json:"name"`
+	Stat            string   `json:"stat"`
+	Tainted         bool     `json:"tainted"`
+	CalculatedValue *float64 `json:"calculated_value"`
+}
+
+// Check is the result of a check of a test run.
+type Check struct {
+	Name          string `json:"name"`
+	MetricSummary struct {
+		SuccessCount int64   `json:"success_count"`
+		FailCount    int64   `json:"fail_count"`
+		SuccessRate  float64 `json:"success_rate"`
+	} `json:"metric_summary"`
+}
+
+// HttpUrl holds the aggregated HTTP metrics of a test run per URL, method,
+// status and scenario.
+type HttpUrl struct {
+	Name              string `json:"name"`
+	Method            string `json:"method"`
+	Status            int    `json:"status"`
+	Scenario          string `json:"scenario"`
+	HttpMetricSummary struct {
+		RequestsCount int64 `json:"requests_count"`
+		Duration      struct {
+			Min  *float64 `json:"min"`
+			Mean *float64 `json:"mean"`
+			P95  *float64 `json:"p95"`
+			P99  *float64 `json:"p99"`
+			Max  *float64 `json:"max"`
+		} `json:"duration"`
+	} `json:"http_metric_summary"`
+}
+
+// LoadZone is a load zone cloud test runs can generate load from.
+type LoadZone struct {
+	Name         string `json:"name"`
+	K6LoadZoneId string `json:"k6_load_zone_id"`
+	Public       bool   `json:"public"`
+}
+
+type collectionResponse[T any] struct {
+	Value []T `json:"value"`
+}
+
+type aggregateResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][]json.Number   `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// HttpError is returned for responses with a non-2xx status code.
+type HttpError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HttpError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s %s responded with %d", e.Method, e.Url, e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s responded with %d: %s", e.Method, e.Url, e.StatusCode, e.Body)
+}
+
+// NewClient creates a client for the API at baseUrl, e.g. https://api.k6.io,
+// authenticating with a Grafana Cloud k6 token for the given stack.
+func NewClient(baseUrl, token, stackId string) *Client {
+	return &Client{
+		baseUrl:        strings.TrimSuffix(baseUrl, "/"),
+		token:          token,
+		stackId:        stackId,
+		httpClient:     &http.Client{Timeout: requestTimeout},
+		maxRetries:     maxRetries,
+		initialBackoff: initialBackoff,
+	}
+}
+
+// WithoutRetries returns a copy of the client sending each request only once,
+// for requests which are repeated by the caller anyway, like polling.
+func (c *Client) WithoutRetries() *Client {
+	client := *c
+	client.maxRetries = 0
+	return &client
+}
+
+// IsFinished reports whether the test run has stopped generating load.
+func (r *TestRun) IsFinished() bool {
+	switch r.Status {
+	case StatusProcessingMetrics, StatusCompleted, StatusAborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseRunUrl returns the test run id and the link to the test run if s contains one.
+func ParseRunUrl(s string) (id string, url string, found bool) {
+	match := runUrlPattern.FindStringSubmatch(s)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[0], true
+}
+
+func (c *Client) GetTestRun(ctx context.Context, id string) (*TestRun, error) {
+	var run TestRun
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/cloud/v6/test_runs/%s", id), &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (c *Client) AbortTestRun(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/cloud/v6/test_runs/%s/abort", id), nil)
+}
+
+func (c *Client) GetThresholds(ctx context.Context, id string) ([]Threshold, error) {
+	return getCollection[Threshold](ctx, c, v5TestRunPath(id, "thresholds"))
+}
+
+func (c *Client) GetChecks(ctx context.Context, id string) ([]Check, error) {
+	return getCollection[Check](ctx, c, v5TestRunPath(id, "checks"))
+}
+
+func (c *Client) GetHttpUrls(ctx context.Context, id string) ([]HttpUrl, error) {
+	return getCollection[HttpUrl](ctx, c, v5TestRunPath(id, "http_urls"))
+}
+
+// v5TestRunPath returns the path of a resource of a test run in the v5 API,
+// which addresses entities the OData way, e.g. /cloud/v5/test_runs(1234)/checks.
+func v5TestRunPath(id string, resource string) string {
+	return fmt.Sprintf("/cloud/v5/test_runs(%s)/%s", url.PathEscape(id), resource)
+}
+
+// odataString quotes s as OData string literal to be used in a path, doubling
+// single quotes and escaping the rest.
+func odataString(s string) string {
+	return "'" + url.PathEscape(strings.ReplaceAll(s, "'", "''")) + "'"
+}
+
+// GetLoadZones lists the load zones available to the stack.
+func (c *Client) GetLoadZones(ctx context.Context) ([]LoadZone, error) {
+	return getCollection[LoadZone](ctx, c, "/cloud/v6/load_zones")
+}
+
+func getCollection[T any](ctx context.Context, c *Client, path string) ([]T, error) {
+	var response collectionResponse[T]
+	if err := c.do(ctx, http.MethodGet, path, &response); err != nil {
+		return nil, err
+	}
+	return response.Value, nil
+}
+
+// QueryAggregate aggregates a metric of a test run with a query like `max` or
+// `histogram_quantile(0.95)` using the v5 API. It returns nil if there is no
+// data for the metric (yet).
+func (c *Client) QueryAggregate(ctx context.Context, id, metric, query string) (*float64, error) {
+	var response aggregateResponse
+	path := v5TestRunPath(id, fmt.Sprintf("query_aggregate_k6(metric=%s,query=%s)", odataString(metric), odataString(query)))
+	if err := c.do(ctx, http.MethodGet, path, &response); err != nil {
+		return nil, err
+	}
+	for _, result := range response.Data.Result {
+		for _, value := range result.Values {
+			if len(value) < 2 {
+				continue
+			}
+			if f, err := value[1].Float64(); err == nil {
+				return &f, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// do sends the request, retrying with exponential backoff on server errors, rate
+// limits and failed connections, and decodes the response into result.
+func (c *Client) do(ctx context.Context, method, path string, result any) error {
+	backoff := c.initialBackoff
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, method, path)
+		if err == nil && !isRetryable(res.StatusCode) {
+			return c.handle(method, path, res, result)
+		}
+		if attempt >= c.maxRetries || ctx.Err() != nil {
+			if err != nil {
+				return err
+			}
+			return c.handle(method, path, res, result)
+		}
+
+		delay := backoff
+		if err != nil {
+			log.Debug().Err(err).Msgf("%s %s failed, retrying in %s.", method, path, delay)
+		} else {
+			delay = retryAfter(res, backoff)
+			log.Debug().Msgf("%s %s responded with %d, retrying in %s.", method, path, res.StatusCode, delay)
+			discard(res)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	req.Header.Set("Accept", "application/json")
+	if c.stackId != "" {
+		req.Header.Set("X-Stack-Id", c.stackId)
+	}
+	return c.httpClient.Do(req)
+}
+
+func (c *Client) handle(method, path string, res *http.Response, result any) error {
+	defer discard(res)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return &HttpError{Method: method, Url: c.baseUrl + path, StatusCode: res.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, c.baseUrl+path, err)
+	}
+	return nil
+}
+
+func isRetryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// retryAfter returns the delay requested by the server's Retry-After header in
+// seconds, or backoff if there is none.
+func retryAfter(res *http.Response, backoff time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maxBackoff)
+	}
+	return backoff
+}
+
+// discard reads the remaining body, so that the connection can be reused, and
+// closes it.
+func discard(res *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+	_ = res.Body.Close()
+}
@@ -0,0 +1,260 @@
+/*
+ * Copyright 2026 steadybit GmbH. All rights reserved.
+ */
+
+package k6cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTestRun(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "Bearer token-1" || req.Header.Get("X-Stack-Id") != "42" {
+				return httpmock.NewStringResponse(401, `{"error": {"message": "unauthorized"}}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"id": 1234, "test_id": 12, "project_id": 3, "status": "completed", "status_details": {"type": "completed", "entered": "2026-03-04T10:11:12Z"}, "result": "failed", "created": "2026-03-04T10:00:00Z", "ended": "2026-03-04T10:11:12Z"}`), nil
+		})
+
+	run, err := NewClient("https://api.k6.io/", "token-1", "42").GetTestRun(context.TODO(), "1234")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), run.Id)
+	assert.Equal(t, int64(12), run.TestId)
+	assert.Equal(t, StatusCompleted, run.Status)
+	assert.Equal(t, StatusCompleted, run.StatusDetails.Type)
+	assert.Equal(t, ResultFailed, *run.Result)
+	assert.True(t, run.IsFinished())
+}
+
+func TestGetTestRunFails(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1", httpmock.NewStringResponder(403, `{"error": {"message": "forbidden"}}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/2", httpmock.NewStringResponder(200, "invalid json"))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/3", httpmock.NewErrorResponder(fmt.Errorf("connection refused")))
+	client := NewClient("https://api.k6.io", "token", "")
+	client.initialBackoff = time.Millisecond
+
+	_, err := client.GetTestRun(context.TODO(), "1")
+	var httpErr *HttpError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, 403, httpErr.StatusCode)
+	assert.Equal(t, `GET https://api.k6.io/cloud/v6/test_runs/1 responded with 403: {"error": {"message": "forbidden"}}`, err.Error())
+
+	_, err = client.GetTestRun(context.TODO(), "2")
+	assert.ErrorContains(t, err, "failed to decode response")
+
+	_, err = client.GetTestRun(context.TODO(), "3")
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestAbortTestRun(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("POST", "https://api.k6.io/cloud/v6/test_runs/1234/abort", httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("POST", "https://api.k6.io/cloud/v6/test_runs/5678/abort", httpmock.NewStringResponder(409, ""))
+	client := NewClient("https://api.k6.io", "token", "42")
+
+	assert.NoError(t, client.AbortTestRun(context.TODO(), "1234"))
+	assert.EqualError(t, client.AbortTestRun(context.TODO(), "5678"), "POST https://api.k6.io/cloud/v6/test_runs/5678/abort responded with 409")
+}
+
+func TestIsFinished(t *testing.T) {
+	for status, finished := range map[string]bool{
+		StatusCreated:           false,
+		StatusQueued:            false,
+		StatusInitializing:      false,
+		StatusRunning:           false,
+		StatusProcessingMetrics: true,
+		StatusCompleted:         true,
+		StatusAborted:           true,
+	} {
+		assert.Equal(t, finished, (&TestRun{Status: status}).IsFinished(), status)
+	}
+}
+
+func TestParseRunUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantId  string
+		wantUrl string
+	}{
+		{name: "legacy", line: "     output: https://app.k6.io/runs/1234567", wantId: "1234567", wantUrl: "https://app.k6.io/runs/1234567"},
+		{name: "grafana cloud", line: "     output: cloud (https://my-stack.grafana.net/a/k6-app/runs/7654321)", wantId: "7654321", wantUrl: "https://my-stack.grafana.net/a/k6-app/runs/7654321"},
+		{name: "no run", line: "     output: https://my-stack.grafana.net/a/k6-app/tests/1", wantId: "", wantUrl: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, url, found := ParseRunUrl(tt.line)
+			assert.Equal(t, tt.wantId, id)
+			assert.Equal(t, tt.wantUrl, url)
+			assert.Equal(t, tt.wantId != "", found)
+		})
+	}
+}
+
+func TestRetriesServerErrorsAndRateLimits(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	calls := 0
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234",
+		func(req *http.Request) (*http.Response, error) {
+			calls++
+			assert.Equal(t, "Bearer token", req.Header.Get("Authorization"), "every attempt must be authenticated")
+			switch calls {
+			case 1:
+				return httpmock.NewStringResponse(503, "unavailable"), nil
+			case 2:
+				res := httpmock.NewStringResponse(429, "slow down")
+				res.Header.Set("Retry-After", "0")
+				return res, nil
+			case 3:
+				return nil, fmt.Errorf("connection reset by peer")
+			default:
+				return httpmock.NewStringResponse(200, `{"id": 1234, "status": "running"}`), nil
+			}
+		})
+	client := NewClient("https://api.k6.io", "token", "")
+	client.initialBackoff = time.Millisecond
+
+	run, err := client.GetTestRun(context.TODO(), "1234")
+
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, run.Status)
+	assert.Equal(t, 4, calls)
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("POST", "https://api.k6.io/cloud/v6/test_runs/1234/abort", httpmock.NewStringResponder(502, "bad gateway"))
+	httpmock.RegisterResponder("POST", "https://api.k6.io/cloud/v6/test_runs/5678/abort", httpmock.NewStringResponder(400, "bad request"))
+	client := NewClient("https://api.k6.io", "token", "")
+	client.initialBackoff = time.Millisecond
+
+	err := client.AbortTestRun(context.TODO(), "1234")
+	assert.EqualError(t, err, "POST https://api.k6.io/cloud/v6/test_runs/1234/abort responded with 502: bad gateway")
+	assert.Equal(t, maxRetries+1, httpmock.GetCallCountInfo()["POST https://api.k6.io/cloud/v6/test_runs/1234/abort"])
+
+	err = client.AbortTestRun(context.TODO(), "5678")
+	assert.EqualError(t, err, "POST https://api.k6.io/cloud/v6/test_runs/5678/abort responded with 400: bad request")
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["POST https://api.k6.io/cloud/v6/test_runs/5678/abort"], "client errors must not be retried")
+}
+
+func TestWithoutRetriesSendsOnce(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234", httpmock.NewStringResponder(503, ""))
+	client := NewClient("https://api.k6.io", "token", "")
+
+	_, err := client.WithoutRetries().GetTestRun(context.TODO(), "1234")
+
+	assert.EqualError(t, err, "GET https://api.k6.io/cloud/v6/test_runs/1234 responded with 503")
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	assert.Equal(t, maxRetries, client.maxRetries, "the original client must keep retrying")
+}
+
+func TestStopsRetryingWhenCancelled(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/test_runs/1234", httpmock.NewStringResponder(500, ""))
+	client := NewClient("https://api.k6.io", "token", "")
+	client.initialBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetTestRun(ctx, "1234")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestQueryAggregate(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(1234)/query_aggregate_k6(metric='vus',query='max')",
+		httpmock.NewStringResponder(200, `{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {"__name__": "vus"}, "values": [[1772619072, 50]]}]}}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(5678)/query_aggregate_k6(metric='vus',query='max')",
+		httpmock.NewStringResponder(200, `{"status": "success", "data": {"resultType": "vector", "result": []}}`))
+	client := NewClient("https://api.k6.io", "token", "")
+
+	value, err := client.QueryAggregate(context.TODO(), "1234", "vus", "max")
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, *value)
+
+	value, err = client.QueryAggregate(context.TODO(), "5678", "vus", "max")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestQueryAggregateEscapesQuery(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	var path string
+	httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+		path = req.URL.EscapedPath()
+		return httpmock.NewStringResponse(200, `{"status": "success", "data": {"resultType": "vector", "result": []}}`), nil
+	})
+	client := NewClient("https://api.k6.io", "token", "")
+
+	_, err := client.QueryAggregate(context.TODO(), "1234", "http_req_duration{status='200'}", "histogram_quantile(0.95)")
+	require.NoError(t, err)
+	assert.Equal(t, "/cloud/v5/test_runs(1234)/query_aggregate_k6(metric='http_req_duration%7Bstatus=%27%27200%27%27%7D',query='histogram_quantile%280.95%29')", path)
+}
+
+func TestGetResults(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(1234)/thresholds",
+		httpmock.NewStringResponder(200, `{"value": [{"id": 1, "name": "http_req_duration: p(95)<500", "stat": "p(95)", "tainted": true, "calculated_value": 812.5}]}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(1234)/checks",
+		httpmock.NewStringResponder(200, `{"value": [{"id": "a", "name": "status is 200", "metric_summary": {"success_count": 95, "fail_count": 5, "success_rate": 0.95}}]}`))
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v5/test_runs(1234)/http_urls",
+		httpmock.NewStringResponder(200, `{"value": [{"name": "https://test.k6.io/", "method": "GET", "status": 200, "scenario": "default", "http_metric_summary": {"requests_count": 100, "duration": {"mean": 120.5, "p95": 180}}}]}`))
+	client := NewClient("https://api.k6.io", "token", "")
+
+	thresholds, err := client.GetThresholds(context.TODO(), "1234")
+	require.NoError(t, err)
+	assert.Equal(t, "http_req_duration: p(95)<500", thresholds[0].Name)
+	assert.True(t, thresholds[0].Tainted)
+	assert.Equal(t, 812.5, *thresholds[0].CalculatedValue)
+
+	checks, err := client.GetChecks(context.TODO(), "1234")
+	require.NoError(t, err)
+	assert.Equal(t, "status is 200", checks[0].Name)
+	assert.Equal(t, int64(5), checks[0].MetricSummary.FailCount)
+
+	urls, err := client.GetHttpUrls(context.TODO(), "1234")
+	require.NoError(t, err)
+	assert.Equal(t, "GET", urls[0].Method)
+	assert.Equal(t, int64(100), urls[0].HttpMetricSummary.RequestsCount)
+	assert.Equal(t, 180.0, *urls[0].HttpMetricSummary.Duration.P95)
+	assert.Nil(t, urls[0].HttpMetricSummary.Duration.P99)
+}
+
+func TestGetLoadZones(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://api.k6.io/cloud/v6/load_zones",
+		httpmock.NewStringResponder(200, `{"value": [{"id": 1, "name": "Ashburn, US", "k6_load_zone_id": "amazon:us:ashburn", "public": true}, {"id": 2, "name": "office", "k6_load_zone_id": "private:office", "public": false}]}`))
+
+	zones, err := NewClient("https://api.k6.io", "token", "42").GetLoadZones(context.TODO())
+
+	require.NoError(t, err)
+	assert.Equal(t, []LoadZone{
+		{Name: "Ashburn, US", K6LoadZoneId: "amazon:us:ashburn", Public: true},
+		{Name: "office", K6LoadZoneId: "private:office"},
+	}, zones)
+}
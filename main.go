@@ -33,9 +33,11 @@ func main() {
 	config.ValidateConfiguration()
 
 	action_kit_sdk.RegisterAction(extk6.NewK6LoadTestRunAction())
+	action_kit_sdk.RegisterAction(extk6.NewK6LoadTestFixedDurationAction())
 	discovery_kit_sdk.Register(extk6.NewDiscovery())
-	if config.Config.CloudApiToken != "" {
+	if len(config.CloudCredentialNames()) > 0 {
 		action_kit_sdk.RegisterAction(extk6.NewK6LoadTestCloudAction())
+		action_kit_sdk.RegisterAction(extk6.NewK6LoadTestCloudOutputAction())
 	}
 
 	exthttp.RegisterRevisionedHandler("/", getExtensionList)